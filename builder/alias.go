@@ -0,0 +1,84 @@
+package builder
+
+import (
+	"sort"
+)
+
+// Get task name of alias in current config
+func resolveAlias(name string) string {
+	return currentMap().resolveAlias(name)
+}
+
+// Get task name of alias, defined by aliases of task or alias of config;
+// name is returned as is if it is task or not alias
+func (config BuildMap) resolveAlias(name string) string {
+	if _, ok := config.Task[name]; ok {
+		return name
+	}
+	if task, ok := config.Alias[name]; ok {
+		return taskRef(task)
+	}
+	names := make([]string, 0, len(config.Task))
+	for task := range config.Task {
+		names = append(names, task)
+	}
+	sort.Strings(names)
+	for _, task := range names {
+		if containsString(config.Task[task].Aliases, name) {
+			return task
+		}
+	}
+	return name
+}
+
+// Get aliases of task, from aliases of task and alias of config, sorted
+func taskAliases(task string) []string {
+	config := currentMap()
+	aliases := append([]string{}, config.Task[task].Aliases...)
+	for alias, target := range config.Alias {
+		if taskRef(target) == task && !containsString(aliases, alias) {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// Check aliases not conflict with tasks or each other, and reference
+// existing task
+func (config BuildMap) checkAliases() []string {
+	var problems []string
+	owners := make(map[string]string)
+	add := func(alias string, task string) {
+		if _, ok := config.Task[alias]; ok {
+			problems = append(problems, "Alias \""+alias+"\" Conflict With Task")
+		} else if owner, ok := owners[alias]; ok && owner != task {
+			problems = append(problems, "Alias \""+alias+"\" Used By Both \""+owner+"\" And \""+task+"\"")
+		}
+		owners[alias] = task
+	}
+	names := make([]string, 0, len(config.Task))
+	for task := range config.Task {
+		names = append(names, task)
+	}
+	sort.Strings(names)
+	for _, task := range names {
+		for _, alias := range config.Task[task].Aliases {
+			add(alias, task)
+		}
+	}
+	aliases := make([]string, 0, len(config.Alias))
+	for alias := range config.Alias {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		task := taskRef(config.Alias[alias])
+		if _, ok := config.Task[task]; !ok {
+			problems = append(problems, "Alias \""+alias+"\" Task \""+task+"\" Not Found")
+			continue
+		}
+		add(alias, task)
+	}
+	return problems
+}
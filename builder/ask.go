@@ -0,0 +1,100 @@
+package builder
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// Prompt define of variable, asked when variable has no value; could be
+// prompt text or object with default
+type Prompt struct {
+	Text    string
+	Default string
+}
+
+// Support prompt text as prompt define
+func (define *Prompt) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&define.Text); err == nil {
+		return nil
+	}
+	type promptDefine Prompt
+	return unmarshal((*promptDefine)(define))
+}
+
+// Support prompt text as prompt define
+func (define *Prompt) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &define.Text); err == nil {
+		return nil
+	}
+	type promptDefine Prompt
+	return json.Unmarshal(data, (*promptDefine)(define))
+}
+
+// Support prompt text as prompt define
+func (define *Prompt) UnmarshalTOML(value interface{}) error {
+	return unmarshalTOML(value, define)
+}
+
+// Fail on variable without value instead of ask
+var nonInteractive bool
+
+// Answers of asked variables, each variable asked once
+var answers = make(map[string]string)
+var answerLock sync.Mutex
+
+// Lookup variable value, ask it if not found and could ask: declared in
+// prompt of config, or stdin is terminal or console
+func lookupOrAsk(name string) (string, bool) {
+	return currentMap().lookupOrAsk(name)
+}
+
+// Lookup variable value in build map, or answer if already asked; never
+// ask
+func (config BuildMap) lookupAnswer(name string) (string, bool) {
+	if value, ok := config.lookupVariable(name); ok {
+		return value, true
+	}
+	answerLock.Lock()
+	defer answerLock.Unlock()
+	value, ok := answers[name]
+	return value, ok
+}
+
+// Lookup variable value in build map, ask it if not found and could ask
+func (config BuildMap) lookupOrAsk(name string) (string, bool) {
+	if value, ok := config.lookupVariable(name); ok {
+		return value, true
+	}
+	if nonInteractive || isRunVariable(name) {
+		return "", false
+	}
+	define, declared := config.Prompt[name]
+	consoleLock.Lock()
+	interactive := consoleStarted || isTerminal(os.Stdin)
+	consoleLock.Unlock()
+	if !declared && !interactive {
+		return "", false
+	}
+	answerLock.Lock()
+	defer answerLock.Unlock()
+	if value, ok := answers[name]; ok {
+		return value, true
+	}
+	text := define.Text
+	if text == "" {
+		text = name
+	}
+	if define.Default != "" {
+		text += " [" + define.Default + "]"
+	}
+	value, err := prompt(text + ":")
+	if err != nil && define.Default == "" {
+		return "", false
+	}
+	if value == "" {
+		value = define.Default
+	}
+	answers[name] = value
+	return value, true
+}
@@ -0,0 +1,2644 @@
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/go-fsnotify/fsnotify"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Color define for log
+const (
+	CLR_W = ""
+	CLR_R = "\x1b[31;1m"
+	CLR_G = "\x1b[32;1m"
+	CLR_B = "\x1b[34;1m"
+	CLR_Y = "\x1b[33;1m"
+	CLR_M = "\x1b[35;1m"
+	CLR_C = "\x1b[36;1m"
+)
+
+// Palette for task prefix when tasks output concurrently
+var taskColors = []string{CLR_C, CLR_M, CLR_Y, CLR_B}
+
+// Color picked for each task name
+var taskColorMap = make(map[string]string)
+var taskColorLock sync.Mutex
+
+// Build define by parse config json
+type BuildMap struct {
+	Variable map[string]string
+	Task     map[string]Task
+	Watch    map[string]Watch
+	// Path patterns never watched, like node_modules or .git
+	Ignore []string
+	// Config files merged before this config, later override earlier
+	Include []string
+	// Config files or directories by namespace, their tasks and variables
+	// are prefixed like api:build, paths resolved against their directory
+	Namespaces map[string]string
+	// Push reload to browser after watch triggered task succeed
+	LiveReload bool
+	// Cron expressions and task references run periodically
+	Schedule map[string]string
+	// Webhooks receive result of build run
+	Notify []Webhook
+	// Prompt text and default of variables asked when they have no value
+	Prompt map[string]Prompt
+	// Short names of tasks, alias to task reference
+	Alias map[string]string
+	// Variables must have value, all missing ones reported when load config
+	RequiredVars []string `yaml:"required_vars" json:"required_vars" toml:"required_vars"`
+	// Variables loaded from env file, also pass to command environment
+	envKeys []string
+}
+
+// Task define, could be command array or object with options
+type Task struct {
+	// Description shown in task list and error messages
+	Desc string
+	// Short names to run the task, like b for build
+	Aliases []string
+	Cmds    []Command
+	// Tasks must complete before the task run
+	Deps []string
+	// Condition command or task reference, skip task if it failed
+	When string
+	// Commands, environment variables and files must exist before run
+	Requires *Requires
+	// Variables must have value before run, all missing ones reported
+	RequiredVars []string `yaml:"required_vars" json:"required_vars" toml:"required_vars"`
+	// Message to confirm by y/N before run, skipped by --yes
+	Confirm string
+	// Platforms the task support, like linux and darwin; skip task on others
+	Platforms []string
+	// File patterns of task input and output, skip task if sources not
+	// changed since last run and generated files exist
+	Sources   []string
+	Generates []string
+	// Save checksums of generated files after run, task is not up to date
+	// if they changed since
+	Fingerprint bool
+	// Store generated files in .build/cache by hash of sources, commands and
+	// variables, restore them instead of run if inputs cached
+	Cache bool
+	// Working directory of commands
+	Dir string
+	// Environment variables of commands
+	Env map[string]string
+	// Not inherit environment of process except variables match env_allow
+	// patterns like PATH or LC_*, for reproducible build
+	CleanEnv bool     `yaml:"clean_env" json:"clean_env" toml:"clean_env"`
+	EnvAllow []string `yaml:"env_allow" json:"env_allow" toml:"env_allow"`
+	// Max duration of each command, like 30s, override --timeout
+	Timeout string
+	// Times to rerun failed command before terminate task, and delay
+	// between each retry, like 1s
+	Retries    int
+	RetryDelay string `yaml:"retry_delay" json:"retry_delay" toml:"retry_delay"`
+	// Hook task reference run after task succeed or failed
+	OnSuccess string `yaml:"on_success" json:"on_success" toml:"on_success"`
+	OnFailure string `yaml:"on_failure" json:"on_failure" toml:"on_failure"`
+	// Continue the task when command failed, like command with - prefix
+	IgnoreErrors bool `yaml:"ignore_errors" json:"ignore_errors" toml:"ignore_errors"`
+	// Connect stdin, stdout and stderr of commands to terminal
+	Interactive bool
+	// Run commands under pseudo terminal, keep their colors and progress
+	Pty bool
+	// Only log stderr of commands, and stdout lines matched by problems
+	Quiet bool
+	// Shell to run commands, like bash or powershell; none run command as
+	// argv without shell, default is sh or cmd on windows
+	Shell string
+	// Log level of task, silent, normal or verbose; override --silent and
+	// --verbose
+	Log string
+	// Static file server started after commands, restart when task run again
+	Serve *Serve
+	// Container to run commands in by docker, image name or object
+	Container *Container
+	// Remote machine to run commands on by ssh, host or object
+	Remote *Remote
+	// Run commands once per combination of variable values, as variables
+	// and environment; combinations run concurrently if matrix_parallel
+	Matrix         map[string][]string
+	MatrixParallel bool `yaml:"matrix_parallel" json:"matrix_parallel" toml:"matrix_parallel"`
+	// Problem matchers parse file and line diagnostics from output, which
+	// are highlighted in log and emitted in JSON log or porcelain
+	Problems []ProblemMatcher
+}
+
+// Support command array as task define
+func (task *Task) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var cmds []Command
+	if err := unmarshal(&cmds); err == nil {
+		task.Cmds = cmds
+		return nil
+	}
+	type taskDefine Task
+	return unmarshal((*taskDefine)(task))
+}
+
+// Support command array as task define
+func (task *Task) UnmarshalJSON(data []byte) error {
+	var cmds []Command
+	if err := json.Unmarshal(data, &cmds); err == nil {
+		task.Cmds = cmds
+		return nil
+	}
+	type taskDefine Task
+	return json.Unmarshal(data, (*taskDefine)(task))
+}
+
+// Support command array as task define
+func (task *Task) UnmarshalTOML(value interface{}) error {
+	return unmarshalTOML(value, task)
+}
+
+// Watch define, could be task reference, list of them or object with
+// options
+type Watch struct {
+	Task TaskRefs
+	// File events trigger the task, default is write, create and rename
+	Events []string
+	// Path patterns not watched for this watch define
+	Exclude []string
+	// Skip task if content of changed file is same as last triggered run,
+	// like file saved without change
+	SkipUnchanged bool `yaml:"skip_unchanged" json:"skip_unchanged" toml:"skip_unchanged"`
+	// Trigger while task running: queue, drop, or cancel running one and
+	// run again; default is --watch-policy
+	Policy string
+}
+
+// Support task reference as watch define
+func (watch *Watch) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&watch.Task); err == nil {
+		return nil
+	}
+	type watchDefine Watch
+	return unmarshal((*watchDefine)(watch))
+}
+
+// Support task reference as watch define
+func (watch *Watch) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &watch.Task); err == nil {
+		return nil
+	}
+	type watchDefine Watch
+	return json.Unmarshal(data, (*watchDefine)(watch))
+}
+
+// Support task reference as watch define
+func (watch *Watch) UnmarshalTOML(value interface{}) error {
+	return unmarshalTOML(value, watch)
+}
+
+// Task references triggered by watch, could be single reference or list
+type TaskRefs []string
+
+// Support single task reference
+func (refs *TaskRefs) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var ref string
+	if err := unmarshal(&ref); err == nil {
+		*refs = TaskRefs{ref}
+		return nil
+	}
+	return unmarshal((*[]string)(refs))
+}
+
+// Support single task reference
+func (refs *TaskRefs) UnmarshalJSON(data []byte) error {
+	var ref string
+	if err := json.Unmarshal(data, &ref); err == nil {
+		*refs = TaskRefs{ref}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(refs))
+}
+
+// Support single task reference
+func (refs *TaskRefs) UnmarshalTOML(value interface{}) error {
+	return unmarshalTOML(value, refs)
+}
+
+// Name of file events could be used in watch define
+var watchEvents = map[string]fsnotify.Op{
+	"write":  fsnotify.Write,
+	"create": fsnotify.Create,
+	"remove": fsnotify.Remove,
+	"rename": fsnotify.Rename,
+	"chmod":  fsnotify.Chmod,
+}
+
+// Get file events trigger the task of watch define
+func (watch *Watch) ops() (fsnotify.Op, error) {
+	if len(watch.Events) == 0 {
+		return fsnotify.Write | fsnotify.Create | fsnotify.Rename, nil
+	}
+	var ops fsnotify.Op
+	for _, name := range watch.Events {
+		op, ok := watchEvents[strings.ToLower(name)]
+		if !ok {
+			return 0, configError("Watch Event \"" + name + "\" Not Supported")
+		}
+		ops |= op
+	}
+	return ops, nil
+}
+
+// Get ignore patterns of watch define, include global ignore patterns
+func (watch *Watch) ignores() []string {
+	var patterns []string
+	for _, pattern := range append(append([]string{}, currentMap().Ignore...), watch.Exclude...) {
+		if pattern, err := parseVariable(pattern); err == nil {
+			patterns = append(patterns, expandPath(pattern))
+		}
+	}
+	return patterns
+}
+
+// Command define in task, could be command string, or group of commands
+// run concurrently, write as nested array or object with parallel key
+type Command struct {
+	Cmd      string
+	Parallel []Command
+	// Commands run concurrently with stdout of each one piped to stdin of
+	// the next, stdout of last one is written to output file if set
+	Pipe   []string
+	Output string
+	// Environment variables of command, override task environment
+	Env map[string]string
+}
+
+// Support command string and nested array as command define, object could
+// have command variants by platform like {linux: ..., windows: ...}
+func (command *Command) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&command.Cmd); err == nil {
+		return nil
+	}
+	if err := unmarshal(&command.Parallel); err == nil {
+		return nil
+	}
+	type commandDefine Command
+	if err := unmarshal((*commandDefine)(command)); err != nil {
+		return err
+	}
+	var variants map[string]interface{}
+	unmarshal(&variants)
+	command.selectPlatform(variants)
+	return nil
+}
+
+// Support command string and nested array as command define, object could
+// have command variants by platform like {linux: ..., windows: ...}
+func (command *Command) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &command.Cmd); err == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, &command.Parallel); err == nil {
+		return nil
+	}
+	type commandDefine Command
+	if err := json.Unmarshal(data, (*commandDefine)(command)); err != nil {
+		return err
+	}
+	var variants map[string]interface{}
+	json.Unmarshal(data, &variants)
+	command.selectPlatform(variants)
+	return nil
+}
+
+// Platforms could be key of command variant and task platforms
+var platforms = []string{"linux", "darwin", "windows", "freebsd", "openbsd", "netbsd"}
+
+// Use command variant of current platform, or default variant; command is
+// empty and skipped if object has variants but none of them match
+func (command *Command) selectPlatform(variants map[string]interface{}) {
+	found := false
+	for _, name := range append(platforms, "default") {
+		if _, ok := variants[name]; ok {
+			found = true
+		}
+	}
+	if !found {
+		return
+	}
+	for _, name := range []string{runtime.GOOS, "default"} {
+		if value, ok := variants[name].(string); ok {
+			command.Cmd = value
+			return
+		}
+	}
+	command.Cmd = ""
+}
+
+// Support command string and nested array as command define
+func (command *Command) UnmarshalTOML(value interface{}) error {
+	return unmarshalTOML(value, command)
+}
+
+// Decoded TOML value is compatible with JSON, reuse JSON unmarshaler
+func unmarshalTOML(value interface{}, define interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, define)
+}
+
+// Storaged data form json config, replaced as a whole when config reload
+// while watch, daemon and server goroutines read it; read by currentMap
+var buildMap BuildMap
+var buildLock sync.RWMutex
+
+// Get build map in use, its maps are not changed after loaded
+func currentMap() BuildMap {
+	buildLock.RLock()
+	defer buildLock.RUnlock()
+	return buildMap
+}
+
+// Replace build map in use by loaded one
+func setBuildMap(define BuildMap) {
+	buildLock.Lock()
+	buildMap = define
+	buildLock.Unlock()
+}
+
+// Variable(${}) match regex
+var varRegex *regexp.Regexp
+
+// Global watcher for file change
+var watcher *fsnotify.Watcher
+
+// Watch dir path map, keep unique; watched from watcher goroutine and
+// reload, so locked by watch dir lock
+var watchDir map[string]bool
+var watchDirLock sync.Mutex
+
+// Mark directory watched, return false if already watched
+func addWatchDir(dir string) bool {
+	watchDirLock.Lock()
+	defer watchDirLock.Unlock()
+	if watchDir[dir] {
+		return false
+	}
+	watchDir[dir] = true
+	return true
+}
+
+// Forget all watched directories, return them for remove from watcher
+func resetWatchDirs() []string {
+	watchDirLock.Lock()
+	defer watchDirLock.Unlock()
+	dirs := make([]string, 0, len(watchDir))
+	for dir := range watchDir {
+		dirs = append(dirs, dir)
+	}
+	watchDir = make(map[string]bool)
+	return dirs
+}
+
+// Hide detail log when running build
+var noDetailLog bool
+
+// Log levels of task
+var logLevels = []string{"silent", "normal", "verbose"}
+
+// Log format, text or json
+var logFormat = "text"
+
+// Count of commands running at the same time
+var runningCMD int32
+
+// Output is prefixed since commands run concurrently, until all of them
+// finished; width of widest prefix to align output
+var prefixing int32
+var prefixWidth int32
+
+// Process of running commands, include daemon commands, channel will be
+// closed when process exit
+var processes = make(map[*exec.Cmd]chan struct{})
+var processLock sync.Mutex
+
+// Process of running daemon commands, group by task
+var daemons = make(map[string]map[*exec.Cmd]chan struct{})
+
+// Wait time for process exit after SIGTERM, then SIGKILL
+const killGrace = 5 * time.Second
+
+// Exit when task fail by config error, even in watch mode
+var failFast bool
+
+// Exit when task terminated by failed command, even in watch mode
+var exitOnError bool
+
+// Only list tasks or print commands, $(command) of variables not run when
+// load config
+var listOnly bool
+
+// Print log without ANSI color
+var noColor bool
+
+// Prefix log line with time
+var timestamps bool
+
+// Triggered tasks waiting for run in batching mode
+var batchTasks = make(map[string]bool)
+var batchChanges = make(map[string][]fileChange)
+var batchTimer *time.Timer
+var batchLock sync.Mutex
+var batchRunLock sync.Mutex
+
+// Task triggered by watch pattern
+type watchTrigger struct {
+	pattern string
+	task    string
+	// Changed file trigger the task
+	change fileChange
+	// Policy if the task is running
+	policy string
+}
+
+// Changed file with event name, write, create, remove, rename or chmod; old
+// file is path before rename if file is created by rename
+type fileChange struct {
+	file    string
+	event   string
+	oldFile string
+}
+
+// Last renamed file, paired with create event right after it
+var lastRename fileChange
+var lastRenameTime time.Time
+var renameLock sync.Mutex
+
+// Watch triggered tasks running, changed files of queued run, and cancel
+// of running ones
+var triggerRunning = make(map[string]bool)
+var triggerPending = make(map[string][]fileChange)
+var triggerCancel = make(map[string]context.CancelFunc)
+var triggerLock sync.Mutex
+
+// Options of started watcher, also of triggered runs; and limit of watch
+// triggered runs at the same time, nil mean no limit
+var watchOpts = &defaultOptions
+var parallelSlots chan struct{}
+var watchOptsLock sync.RWMutex
+
+// Set options of watch, by Configure or started watcher
+func setWatchOptions(opts *Options) {
+	watchOptsLock.Lock()
+	defer watchOptsLock.Unlock()
+	watchOpts = opts
+	parallelSlots = nil
+	if opts.MaxParallel > 0 {
+		parallelSlots = make(chan struct{}, opts.MaxParallel)
+	}
+}
+
+// Get options of watch and limit of triggered runs
+func watchOptions() (*Options, chan struct{}) {
+	watchOptsLock.RLock()
+	defer watchOptsLock.RUnlock()
+	return watchOpts, parallelSlots
+}
+
+// Coalesce changes of same pattern in debounce window into one task run
+var debounceTimers = make(map[string]*time.Timer)
+var debounceChanges = make(map[string][]fileChange)
+var debounceLock sync.Mutex
+
+// Error caused by config, like undefined task or variable
+type configError string
+
+func (err configError) Error() string {
+	return string(err)
+}
+
+// Error of task terminated by failed command
+type taskError struct {
+	task string
+	err  error
+}
+
+func (err *taskError) Error() string {
+	return "Task \"" + err.task + "\"" + describeTask(err.task) + " Terminated: " + err.err.Error()
+}
+
+// Get description of task in parentheses for message, or empty
+func describeTask(task string) string {
+	if desc := currentMap().Task[task].Desc; desc != "" {
+		return " (" + desc + ")"
+	}
+	return ""
+}
+
+// Exit code of failed command, 1 if command not exit normally
+func (err *taskError) exitCode() int {
+	return exitCode(err.err)
+}
+
+// A build run, for all tasks run in one trigger
+type buildRun struct {
+	*runState
+	// Built-in variables of the run, like changed file
+	vars map[string]string
+	// Variables of matrix combination, override all other variables
+	matrix map[string]string
+	// Tasks calling current task by reference, for detect circular reference
+	stack []string
+	// Run commands in own process group, to kill their children when
+	// context of run is done
+	group bool
+}
+
+// State of a build run, shared by all tasks and matrix combinations
+type runState struct {
+	// Deps already run or running, closed when finish
+	deps     map[string]chan struct{}
+	depsErr  map[string]error
+	depsLock sync.Mutex
+	// Elapsed time of tasks and commands, for profile summary
+	timings    []timing
+	timingLock sync.Mutex
+	// First terminated task, even if it not break caller task
+	failed   *taskError
+	failLock sync.Mutex
+	// Results of finished tasks and commands run by task, for summary
+	results    []taskResult
+	commands   map[string]int
+	steps      map[string][]HistoryCommand
+	resultLock sync.Mutex
+	// Source trigger the run, like command, watch or schedule
+	trigger string
+	// Options of the run, of runner or watcher
+	opts *Options
+	// Done when run canceled, running commands are terminated and rest of
+	// tasks not run
+	ctx context.Context
+}
+
+// Record terminated task of build run, keep the first one
+func (run *buildRun) fail(err *taskError) {
+	run.failLock.Lock()
+	if run.failed == nil {
+		run.failed = err
+	}
+	run.failLock.Unlock()
+}
+
+// Forget terminated task of build run, failed condition task not fail the
+// run
+func (run *buildRun) unfail(err error) {
+	run.failLock.Lock()
+	if failed, ok := err.(*taskError); ok && run.failed == failed {
+		run.failed = nil
+	}
+	run.failLock.Unlock()
+}
+
+// Elapsed time of a task or a command in task
+type timing struct {
+	name    string
+	elapsed time.Duration
+}
+
+// Record elapsed time of task or command in build run
+func (run *buildRun) record(name string, elapsed time.Duration) {
+	run.timingLock.Lock()
+	run.timings = append(run.timings, timing{name, elapsed})
+	run.timingLock.Unlock()
+}
+
+// Print timings of build run, slowest first
+func (run *buildRun) printProfile() {
+	run.timingLock.Lock()
+	defer run.timingLock.Unlock()
+	if len(run.timings) == 0 {
+		return
+	}
+	sort.SliceStable(run.timings, func(i, j int) bool {
+		return run.timings[i].elapsed > run.timings[j].elapsed
+	})
+	log(CLR_W, "Profile:")
+	for _, t := range run.timings {
+		log(CLR_W, fmt.Sprintf("%10s  %s", formatElapsed(t.elapsed), t.name))
+	}
+}
+
+// Format elapsed time for log, round to millisecond
+func formatElapsed(elapsed time.Duration) string {
+	if elapsed < time.Millisecond {
+		return elapsed.Round(time.Microsecond).String()
+	}
+	return elapsed.Round(time.Millisecond).String()
+}
+
+// Create build run, file is changed file trigger the run or empty; options
+// are of watch, runner replace them
+func newBuildRun(file string) *buildRun {
+	vars := map[string]string{
+		"FILE":      "",
+		"FILE_DIR":  "",
+		"EVENT":     "",
+		"OLD_FILE":  "",
+		"TIMESTAMP": time.Now().Format("20060102150405"),
+	}
+	if file != "" {
+		vars["FILE"] = filepath.Clean(file)
+		vars["FILE_DIR"] = filepath.Dir(file)
+	}
+	vars["FILES"] = vars["FILE"]
+	opts, _ := watchOptions()
+	return &buildRun{
+		runState: &runState{
+			deps:     make(map[string]chan struct{}),
+			depsErr:  make(map[string]error),
+			commands: make(map[string]int),
+			steps:    make(map[string][]HistoryCommand),
+			ctx:      context.Background(),
+			trigger:  "command",
+			opts:     opts,
+		},
+		vars: vars,
+	}
+}
+
+// New build run triggered by changed files, ${FILES} is all of them split
+// by space, ${FILE}, ${EVENT} and ${OLD_FILE} are of the last one
+func newFilesRun(changes []fileChange) *buildRun {
+	if len(changes) == 0 {
+		return newBuildRun("")
+	}
+	last := changes[len(changes)-1]
+	run := newBuildRun(last.file)
+	run.trigger = "watch"
+	run.vars["EVENT"] = last.event
+	if last.oldFile != "" {
+		run.vars["OLD_FILE"] = filepath.Clean(last.oldFile)
+	}
+	seen := make(map[string]bool)
+	var unique []string
+	for _, change := range changes {
+		if file := filepath.Clean(change.file); !seen[file] {
+			seen[file] = true
+			unique = append(unique, file)
+		}
+	}
+	sort.Strings(unique)
+	run.vars["FILES"] = strings.Join(unique, " ")
+	return run
+}
+
+// Get change of file system event, create right after rename is paired
+// with renamed file
+func eventChange(event fsnotify.Event) fileChange {
+	change := fileChange{file: event.Name}
+	switch {
+	case event.Op&fsnotify.Remove != 0:
+		change.event = "remove"
+	case event.Op&fsnotify.Rename != 0:
+		change.event = "rename"
+	case event.Op&fsnotify.Create != 0:
+		change.event = "create"
+	case event.Op&fsnotify.Write != 0:
+		change.event = "write"
+	default:
+		change.event = "chmod"
+	}
+	renameLock.Lock()
+	defer renameLock.Unlock()
+	if change.event == "rename" {
+		lastRename, lastRenameTime = change, time.Now()
+	} else if change.event == "create" && lastRename.file != "" && time.Since(lastRenameTime) < 100*time.Millisecond {
+		change.oldFile = lastRename.file
+		lastRename = fileChange{}
+	}
+	return change
+}
+
+// Replace ${} reference to real value, include built-in variables of run;
+// matrix variables take precedence
+func (run *buildRun) parseVariable(str string) (string, error) {
+	return run.replaceVariables(str, lookupOrAsk)
+}
+
+// Replace ${} reference of build run, lookup value of config variable
+func (run *buildRun) replaceVariables(str string, lookup func(string) (string, bool)) (string, error) {
+	for _, ref := range varRegex.FindAllString(str, -1) {
+		name, defValue, hasDefault := splitDefault(extractRef(ref))
+		if value, ok := run.matrix[name]; ok {
+			if value == "" && hasDefault {
+				value = defValue
+			}
+			str = strings.Replace(str, ref, value, -1)
+		}
+	}
+	str, err := currentMap().replaceVariables(str, lookup)
+	if err != nil {
+		return "", err
+	}
+	for _, ref := range varRegex.FindAllString(str, -1) {
+		name, defValue, hasDefault := splitDefault(extractRef(ref))
+		if value, ok := run.vars[name]; ok {
+			if value == "" && hasDefault {
+				value = defValue
+			}
+			str = strings.Replace(str, ref, value, 1)
+		}
+	}
+	return run.parseTemplate(str)
+}
+
+// Print colorful log
+func log(color string, info interface{}) {
+	logTask("", -1, "", color, info)
+}
+
+// Log record in JSON log format, index is command index in task
+type logRecord struct {
+	Level   string   `json:"level"`
+	Task    string   `json:"task,omitempty"`
+	Index   *int     `json:"index,omitempty"`
+	Message string   `json:"message"`
+	Time    string   `json:"time"`
+	Problem *problem `json:"problem,omitempty"`
+}
+
+// Print log of task, index is command index in task or -1, prefix is only
+// shown in text log format
+func logTask(task string, index int, prefix string, color string, info interface{}) {
+	if color == CLR_G && hideDetailLog(task) {
+		return
+	}
+	if eventsEnabled() {
+		logEvent(task, index, logType(color), info)
+	}
+	printTask(task, index, prefix, color, info)
+}
+
+// Check if detail log of task is hidden, by log level of task or --silent
+func hideDetailLog(task string) bool {
+	switch currentMap().Task[task].Log {
+	case "silent":
+		return true
+	case "normal", "verbose":
+		return false
+	}
+	return noDetailLog
+}
+
+// Check if command line of task is printed before execute, by log level of
+// task or verbose option of run
+func verboseLog(run *buildRun, task string) bool {
+	switch currentMap().Task[task].Log {
+	case "verbose":
+		return true
+	case "silent", "normal":
+		return false
+	}
+	return run.opts.Verbose
+}
+
+// Print log of task by log format, or only write to log file in porcelain
+// mode which log is emitted as event
+func printTask(task string, index int, prefix string, color string, info interface{}) {
+	outputType := logType(color)
+	if logger != nil {
+		logger.Log(LogEntry{time.Now(), outputType, task, index, fmt.Sprint(info)})
+		plain := fmt.Sprintf("%s: %s", outputType, info)
+		if logOutput != nil {
+			logOutput.writeLine(plain)
+		}
+		tailLog(plain)
+		return
+	}
+	if porcelain {
+		writePlain(color, fmt.Sprint(info))
+		return
+	}
+	if logFormat == "json" {
+		record := logRecord{
+			Level:   strings.ToLower(outputType),
+			Task:    task,
+			Message: fmt.Sprint(info),
+			Time:    time.Now().Format(time.RFC3339Nano),
+		}
+		if index >= 0 {
+			record.Index = &index
+		}
+		line, _ := json.Marshal(record)
+		writeLog(string(line), string(line))
+		return
+	}
+	var plainPrefix string
+	if prefix != "" {
+		// Align output of tasks with different name length
+		padding := " "
+		if width := int(atomic.LoadInt32(&prefixWidth)); width > len(prefix) {
+			padding += strings.Repeat(" ", width-len(prefix))
+		}
+		plainPrefix = "[" + prefix + "]" + padding
+		prefix = fmt.Sprintf("%s[%s]%s%s", taskColor(prefix), prefix, "\x1b[0m", padding)
+	}
+	if timestamps {
+		outputType = time.Now().Format("15:04:05.000") + " " + outputType
+	}
+	plain := fmt.Sprintf("%s: %s%s", outputType, plainPrefix, info)
+	if noColor {
+		writeLog(plain, plain)
+		return
+	}
+	line := fmt.Sprintf("%s: %s%s%s%s", outputType, prefix, color, info, "\x1b[0m")
+	writeLog(line, plain)
+}
+
+// Get log type of color, LOG, ERR, RUN or CMD
+func logType(color string) string {
+	switch color {
+	case CLR_W:
+		return "LOG"
+	case CLR_R:
+		return "ERR"
+	case CLR_G:
+		return "RUN"
+	case CLR_B:
+		return "CMD"
+	}
+	return ""
+}
+
+// Print log line, and write line without color to log file and tail for
+// webhook
+func writeLog(line string, plain string) {
+	if !tuiActive() {
+		fmt.Println(line)
+	}
+	if logOutput != nil {
+		logOutput.writeLine(plain)
+	}
+	tailLog(plain)
+}
+
+// Pick a color from palette for task name, keep same color for same task
+func taskColor(task string) string {
+	taskColorLock.Lock()
+	defer taskColorLock.Unlock()
+	color, ok := taskColorMap[task]
+	if !ok {
+		color = taskColors[len(taskColorMap)%len(taskColors)]
+		taskColorMap[task] = color
+	}
+	return color
+}
+
+// Count command started
+func startCMD() {
+	if atomic.AddInt32(&runningCMD, 1) > 1 {
+		atomic.StoreInt32(&prefixing, 1)
+	}
+}
+
+// Count command finished, stop prefix output when all finished
+func finishCMD() {
+	if atomic.AddInt32(&runningCMD, -1) == 0 {
+		atomic.StoreInt32(&prefixing, 0)
+	}
+}
+
+// Get prefix for output line of task, when commands run concurrently, so
+// tail output of concurrent commands still has prefix
+func outputPrefix(task string) string {
+	if atomic.LoadInt32(&prefixing) == 0 {
+		return ""
+	}
+	for {
+		width := atomic.LoadInt32(&prefixWidth)
+		if int32(len(task)) <= width || atomic.CompareAndSwapInt32(&prefixWidth, width, int32(len(task))) {
+			return task
+		}
+	}
+}
+
+// Last build run failed, for on-error clear policy
+var lastRunFailed int32
+
+// Clear log before run by clear policy
+func clearBeforeRun() {
+	opts, _ := watchOptions()
+	switch opts.Clear {
+	case "never":
+		return
+	case "on-error":
+		if atomic.LoadInt32(&lastRunFailed) == 0 {
+			return
+		}
+	}
+	clear()
+}
+
+// Clear terminal and its scrollback by ANSI escape code, skipped if stdout
+// is not terminal or could not handle escape code
+func clear() {
+	if porcelain || tuiActive() || !isTerminal(os.Stdout) || !enableColor(os.Stdout) {
+		return
+	}
+	fmt.Print("\x1b[H\x1b[2J\x1b[3J")
+}
+
+// Add directories of watch patterns to watcher
+func addWatches() error {
+	for path, watch := range currentMap().Watch {
+		if _, err := watch.ops(); err != nil {
+			return err
+		}
+		path, err := parseVariable(path)
+		if err != nil {
+			return err
+		}
+		path = expandPath(path)
+		dirPaths, err := watchPaths(path, watch.ignores())
+		if err != nil {
+			return err
+		}
+		for _, dirPath := range dirPaths {
+			if addWatchDir(dirPath) {
+				log(CLR_G, "Watching file on "+dirPath)
+				if err := watcher.Add(dirPath); err != nil {
+					log(CLR_R, err.Error())
+				}
+			}
+		}
+	}
+	watchConfigFiles()
+	return nil
+}
+
+// Remove all watched directories, then watch by current config; polling
+// always scan by current config
+func restartWatch() error {
+	if opts, _ := watchOptions(); opts.Poll > 0 {
+		return nil
+	}
+	for _, dir := range resetWatchDirs() {
+		watcher.Remove(dir)
+	}
+	return addWatches()
+}
+
+// When file change, run task to handle
+func handleWatch(event fsnotify.Event) {
+	// Get change file info
+	fileName := event.Name
+	change := eventChange(event)
+	handleConfigChange(event)
+	// Watch new created directory
+	if event.Op&fsnotify.Create == fsnotify.Create {
+		if info, err := os.Stat(fileName); err == nil && info.IsDir() {
+			watchNewDir(fileName)
+		}
+	}
+	// If changed file path match define in build map, run task
+	opts, _ := watchOptions()
+	var triggers []watchTrigger
+	for define, watch := range currentMap().Watch {
+		if ops, err := watch.ops(); err != nil || event.Op&ops == 0 {
+			continue
+		}
+		pattern, err := parseVariable(define)
+		if err != nil {
+			log(CLR_R, err.Error())
+			continue
+		}
+		pattern = expandPath(pattern)
+		if ok, err := matchWatch(pattern, fileName); err == nil && ok && !matchIgnore(watch.ignores(), fileName) {
+			for _, ref := range watch.Task {
+				if taskName := extractRef(ref); taskName != "" {
+					if watch.SkipUnchanged && unchangedContent(taskName, fileName) {
+						logTask(taskName, -1, "", CLR_G, taskName+" SKIPPED, "+fileName+" Not Changed")
+						continue
+					}
+					policy := watch.Policy
+					if policy == "" {
+						policy = opts.WatchPolicy
+					}
+					trigger := watchTrigger{define, taskName, change, policy}
+					emitWatchTriggered(trigger)
+					triggers = append(triggers, trigger)
+				}
+			}
+		}
+	}
+	if opts.WatchTriggerAll {
+		var tasks []string
+		for _, trigger := range triggers {
+			tasks = append(tasks, trigger.task)
+		}
+		batchTrigger(tasks, change)
+		return
+	}
+	// Exec task by task name
+	for _, trigger := range triggers {
+		if opts.Debounce > 0 {
+			debounceTrigger(trigger, opts.Debounce)
+		} else {
+			triggerTask(trigger.task, []fileChange{trigger.change}, trigger.policy)
+		}
+	}
+}
+
+// Run task triggered by watched file changes, if the task is running, queue
+// a run after it, drop the trigger, or cancel the running one and run again
+// by watch policy
+func triggerTask(task string, changes []fileChange, policy string) {
+	triggerLock.Lock()
+	if triggerRunning[task] {
+		switch policy {
+		case "queue":
+			triggerPending[task] = append(triggerPending[task], changes...)
+		case "cancel":
+			triggerPending[task] = append(triggerPending[task], changes...)
+			if cancel, ok := triggerCancel[task]; ok {
+				logTask(task, -1, "", CLR_G, task+" CANCELED, Superseded By New Change")
+				cancel()
+				delete(triggerCancel, task)
+			}
+		default:
+			logTask(task, -1, "", CLR_G, task+" DROPPED, Already Running")
+		}
+		triggerLock.Unlock()
+		return
+	}
+	triggerRunning[task] = true
+	triggerLock.Unlock()
+	go func() {
+		for {
+			_, slots := watchOptions()
+			if slots != nil {
+				slots <- struct{}{}
+			}
+			// Run could be canceled by newer trigger, commands are run in
+			// own process group to terminate their children
+			run := newFilesRun(changes)
+			ctx, cancel := context.WithCancel(context.Background())
+			run.ctx, run.group = ctx, policy == "cancel"
+			if len(changes) == 0 {
+				run.trigger = "schedule"
+			}
+			// Scheduled run has no changed file, keep log of last run
+			if run.trigger != "schedule" {
+				clearBeforeRun()
+			}
+			triggerLock.Lock()
+			triggerCancel[task] = cancel
+			triggerLock.Unlock()
+			start := time.Now()
+			err := runTaskLimited(run, task)
+			if slots != nil {
+				<-slots
+			}
+			if !run.canceled() {
+				notifyResult(task, err, time.Since(start))
+				if err == nil && currentMap().LiveReload {
+					liveReload(lastFile(changes))
+				}
+				handleError(err)
+			}
+			cancel()
+			// Run queued trigger, changes in the meantime run once
+			triggerLock.Lock()
+			delete(triggerCancel, task)
+			next, ok := triggerPending[task]
+			delete(triggerPending, task)
+			if !ok {
+				delete(triggerRunning, task)
+				triggerLock.Unlock()
+				return
+			}
+			triggerLock.Unlock()
+			changes = next
+		}
+	}()
+}
+
+// Delay task of pattern until no more change in debounce window, each task
+// of pattern is delayed separately
+func debounceTrigger(trigger watchTrigger, debounce time.Duration) {
+	debounceLock.Lock()
+	defer debounceLock.Unlock()
+	key := trigger.pattern + " -> " + trigger.task
+	debounceChanges[key] = append(debounceChanges[key], trigger.change)
+	if timer, ok := debounceTimers[key]; ok && timer.Stop() {
+		timer.Reset(debounce)
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(debounce, func() {
+		debounceLock.Lock()
+		changes := debounceChanges[key]
+		if debounceTimers[key] == timer {
+			delete(debounceTimers, key)
+			delete(debounceChanges, key)
+		}
+		debounceLock.Unlock()
+		triggerTask(trigger.task, changes, trigger.policy)
+	})
+	debounceTimers[key] = timer
+}
+
+// Add new created directory and its sub directories to watcher, if files
+// in it could match watch pattern
+func watchNewDir(dir string) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if !shouldWatchDir(path) || !addWatchDir(path) {
+			return nil
+		}
+		log(CLR_G, "Watching file on "+path)
+		if err := watcher.Add(path); err != nil {
+			log(CLR_R, err.Error())
+		}
+		return nil
+	})
+}
+
+// Check if files in directory could match any watch pattern
+func shouldWatchDir(dir string) bool {
+	for pattern, watch := range currentMap().Watch {
+		pattern, err := parseVariable(pattern)
+		if err != nil || matchIgnore(watch.ignores(), dir) {
+			continue
+		}
+		pattern = expandPath(pattern)
+		if isRecursive(pattern) {
+			// Compare in absolute form, directory and pattern could differ
+			base, err := filepath.Abs(patternBase(pattern))
+			if err != nil {
+				continue
+			}
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				continue
+			}
+			if rel, err := filepath.Rel(base, abs); err == nil && !strings.HasPrefix(rel, "..") {
+				return true
+			}
+		} else if ok, err := matchWatch(filepath.Dir(pattern), dir); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Collect triggered tasks until watch window passed, then run them
+func batchTrigger(tasks []string, change fileChange) {
+	batchLock.Lock()
+	defer batchLock.Unlock()
+	for _, task := range tasks {
+		batchTasks[task] = true
+		batchChanges[task] = append(batchChanges[task], change)
+	}
+	if batchTimer == nil && len(batchTasks) > 0 {
+		opts, _ := watchOptions()
+		batchTimer = time.AfterFunc(opts.WatchWindow, runBatch)
+	}
+}
+
+// Run each collected task once, in order of task name
+func runBatch() {
+	batchLock.Lock()
+	tasks := make([]string, 0, len(batchTasks))
+	for task := range batchTasks {
+		tasks = append(tasks, task)
+	}
+	changes := batchChanges
+	batchChanges = make(map[string][]fileChange)
+	batchTasks = make(map[string]bool)
+	batchTimer = nil
+	batchLock.Unlock()
+	sort.Strings(tasks)
+	// Batches run one by one, not interleave with each other
+	batchRunLock.Lock()
+	defer batchRunLock.Unlock()
+	clearBeforeRun()
+	succeed := true
+	for _, task := range tasks {
+		start := time.Now()
+		err := runTaskLimited(newFilesRun(changes[task]), task)
+		notifyResult(task, err, time.Since(start))
+		succeed = succeed && err == nil
+		handleError(err)
+	}
+	if succeed && currentMap().LiveReload && len(tasks) > 0 {
+		liveReload(lastFile(changes[tasks[len(tasks)-1]]))
+	}
+}
+
+// Get last changed file, or empty
+func lastFile(changes []fileChange) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	return changes[len(changes)-1].file
+}
+
+// Replace ${} refrence to real value
+func parseVariable(str string) (string, error) {
+	config := currentMap()
+	return config.replaceVariables(str, config.lookupOrAsk)
+}
+
+// Replace ${} refrence to value of lookup
+func (config BuildMap) replaceVariables(str string, lookup func(string) (string, bool)) (string, error) {
+	refAry := varRegex.FindAllString(str, -1)
+	if len(refAry) > 0 {
+		for _, ref := range refAry {
+			varName, defValue, hasDefault := splitDefault(extractRef(ref))
+			// Keep built-in variable of build run, parsed when run
+			if _, ok := config.Variable[varName]; !ok && isRunVariable(varName) {
+				continue
+			}
+			if varValue, ok := lookup(varName); ok && (varValue != "" || !hasDefault) {
+				str = strings.Replace(str, ref, varValue, 1)
+			} else if hasDefault {
+				str = strings.Replace(str, ref, defValue, 1)
+			} else {
+				return "", configError("Variable \"" + varName + "\" Not Found")
+			}
+		}
+	}
+	return str, nil
+}
+
+// Lookup variable value, ${env:NAME} or variable not defined in config will
+// use environment variable
+func lookupVariable(name string) (string, bool) {
+	return currentMap().lookupVariable(name)
+}
+
+// Lookup variable value in build map
+func (config BuildMap) lookupVariable(name string) (string, bool) {
+	if strings.HasPrefix(name, "env:") {
+		return os.LookupEnv(name[len("env:"):])
+	}
+	if value, ok := config.Variable[name]; ok {
+		return value, true
+	}
+	if value, ok := builtinVariables[name]; ok {
+		return value, true
+	}
+	return os.LookupEnv(name)
+}
+
+// Built-in variables, config variable with same name override them
+var builtinVariables = map[string]string{
+	"OS":   runtime.GOOS,
+	"ARCH": runtime.GOARCH,
+}
+
+// Check if variable is built-in variable of build run, like FILE, FILE_DIR,
+// FILES, EVENT, OLD_FILE and TIMESTAMP
+func isRunVariable(name string) bool {
+	switch name {
+	case "FILE", "FILE_DIR", "FILES", "EVENT", "OLD_FILE", "TIMESTAMP":
+		return true
+	}
+	return false
+}
+
+// Resolve nested variables to fixed point, independent of map order
+func (config BuildMap) resolveVariables() error {
+	names := make([]string, 0, len(config.Variable))
+	for name := range config.Variable {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	resolved := make(map[string]bool)
+	for _, name := range names {
+		if err := config.resolveVariable(name, nil, resolved); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Expand all ${} reference in variable, stack keep the reference chain for
+// detect circular reference
+func (config BuildMap) resolveVariable(name string, stack []string, resolved map[string]bool) error {
+	for _, ref := range stack {
+		if ref == name {
+			chain := strings.Join(append(stack, name), " -> ")
+			return fmt.Errorf("Variable Circular Reference: %s", chain)
+		}
+	}
+	if resolved[name] {
+		return nil
+	}
+	value, ok := config.Variable[name]
+	if !ok {
+		return fmt.Errorf("Variable \"%s\" Not Found", name)
+	}
+	stack = append(stack, name)
+	for _, ref := range varRegex.FindAllString(value, -1) {
+		refName, defValue, hasDefault := splitDefault(extractRef(ref))
+		if _, ok := config.Variable[refName]; !ok {
+			if isRunVariable(refName) {
+				continue
+			}
+			envValue, ok := config.lookupVariable(refName)
+			if !ok && !hasDefault {
+				envValue, ok = config.lookupOrAsk(refName)
+			}
+			if !ok && !hasDefault {
+				return fmt.Errorf("Variable \"%s\" Not Found", refName)
+			}
+			if envValue == "" && hasDefault {
+				envValue = defValue
+			}
+			value = strings.Replace(value, ref, envValue, -1)
+			continue
+		}
+		if err := config.resolveVariable(refName, stack, resolved); err != nil {
+			return err
+		}
+		refValue := config.Variable[refName]
+		if refValue == "" && hasDefault {
+			refValue = defValue
+		}
+		value = strings.Replace(value, ref, refValue, -1)
+	}
+	// Variable write as $(command) use trimmed output of the command, kept
+	// as is in dry run and list mode which not run anything
+	if strings.HasPrefix(value, "$(") && strings.HasSuffix(value, ")") && !listOnly {
+		cmd := shellCommand(value[2 : len(value)-1])
+		cmd.Env = config.commandEnv()
+		cmd.Stderr = os.Stderr
+		output, err := cmd.Output()
+		if err != nil {
+			return fmt.Errorf("Variable \"%s\" Command Failed: %s", name, err)
+		}
+		value = strings.TrimSpace(string(output))
+	}
+	config.Variable[name] = value
+	resolved[name] = true
+	return nil
+}
+
+// Expand leading ~ to home directory and $VAR or ${VAR} to environment
+// variable, like what shell does for path
+func expandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = home + path[1:]
+		}
+	}
+	return os.ExpandEnv(path)
+}
+
+// Extract ${} refrence
+func extractRef(str string) string {
+	if len(str) > 3 && str[0:2] == "${" && string(str[len(str)-1]) == "}" {
+		str = strings.Replace(str, "${", "", -1)
+		str = strings.Replace(str, "}", "", -1)
+		return str
+	}
+	return ""
+}
+
+// Split variable name and default value, write as ${NAME:-default}
+func splitDefault(ref string) (string, string, bool) {
+	if idx := strings.Index(ref, ":-"); idx >= 0 {
+		return ref[:idx], ref[idx+2:], true
+	}
+	return ref, "", false
+}
+
+// Run task defined in build map, failed command terminate the task
+func runTask(run *buildRun, task string, forceDaemon bool) error {
+	// If task has # prefix, run in non-block mode
+	daemon := false
+	if string(task[0]) == "#" {
+		daemon = true
+		task = task[1:]
+	} else if forceDaemon {
+		daemon = true
+	}
+	if run.canceled() {
+		return errCanceled
+	}
+	task = resolveAlias(task)
+	if define, ok := currentMap().Task[task]; ok {
+		if len(define.Platforms) > 0 && !supportPlatform(define.Platforms) {
+			logTask(task, -1, "", CLR_G, task+" SKIPPED ON "+runtime.GOOS)
+			return nil
+		}
+		for _, name := range run.stack {
+			if name == task {
+				chain := strings.Join(append(run.stack, task), " -> ")
+				return configError("Task Circular Reference: " + chain)
+			}
+		}
+		scoped := *run
+		scoped.stack = append(append([]string{}, run.stack...), task)
+		run = &scoped
+		// Ask before run dangerous task, before its deps and condition
+		if define.Confirm != "" && !run.opts.DryRun {
+			message, err := run.parseVariable(define.Confirm)
+			if err != nil {
+				return err
+			}
+			if !confirm(run, message) {
+				return errors.New("Task \"" + task + "\" Not Confirmed")
+			}
+		}
+		if missing := missingVars(run, define.RequiredVars, lookupOrAsk); len(missing) > 0 {
+			return configError("Task \"" + task + "\"" + describeTask(task) + " Required Variables Not Set: " + strings.Join(missing, ", "))
+		}
+		if err := checkRequires(run, task, define.Requires); err != nil {
+			return err
+		}
+		if run.opts.Restart {
+			stopDaemons(task, "RESTARTING")
+		}
+		// Run deps before task, each dep run once in a build run
+		if err := runDeps(run, task); err != nil {
+			return err
+		}
+		// Skip task if sources not changed; cached task is decided by cache
+		// key, which also cover variables and commands
+		cached := define.Cache && !daemon && !run.opts.DryRun
+		var checksum string
+		if len(define.Sources) > 0 {
+			upToDate, sum, err := isUpToDate(task, define)
+			if err != nil {
+				return err
+			}
+			if upToDate && !cached {
+				logTask(task, -1, "", CLR_G, task+" UP TO DATE")
+				run.result(task, "up to date", 0)
+				return nil
+			}
+			checksum = sum
+		}
+		// Skip task if condition command or referenced task failed
+		if define.When != "" {
+			var err error
+			if ref := extractRef(define.When); ref != "" && !strings.HasPrefix(ref, "env:") {
+				err = runTask(run, ref, false)
+				run.unfail(err)
+			} else {
+				err = runCMD(run, task, -1, Command{Cmd: define.When}, false, true)
+			}
+			if err != nil {
+				if _, ok := err.(configError); ok {
+					return err
+				}
+				logTask(task, -1, "", CLR_G, task+" SKIPPED")
+				run.result(task, "skipped", 0)
+				return nil
+			}
+		}
+		// Restore generated files if inputs cached
+		var cache string
+		if cached {
+			key, err := cacheKey(run, task, define)
+			if err != nil {
+				return err
+			}
+			restored, err := restoreCache(key)
+			if err != nil {
+				return err
+			}
+			if restored {
+				logTask(task, -1, "", CLR_G, task+" RESTORED FROM CACHE")
+				run.result(task, "cached", 0)
+				if checksum != "" {
+					saveChecksum(task, checksum)
+				}
+				return nil
+			}
+			cache = key
+		}
+		start := time.Now()
+		emitTaskStarted(task)
+		err := runMatrix(run, task, define, daemon)
+		if err == nil && !daemon && !run.opts.DryRun {
+			err = verifyGenerates(run, task, define)
+		}
+		if err == nil && define.Serve != nil {
+			err = startServer(run, task, define.Serve)
+		}
+		if !daemon && err == nil {
+			elapsed := time.Since(start)
+			run.record(task, elapsed)
+			run.result(task, "done", elapsed)
+			logTask(task, -1, "", CLR_G, task+" done in "+formatElapsed(elapsed))
+		}
+		if _, ok := err.(*taskError); ok && !daemon {
+			run.result(task, "failed", time.Since(start))
+		}
+		if err == errCanceled && !daemon {
+			run.result(task, "canceled", time.Since(start))
+		}
+		if _, ok := err.(*taskError); ok {
+			runHook(run, task, define.OnFailure)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if checksum != "" && !run.opts.DryRun {
+			if err := saveChecksum(task, checksum); err != nil {
+				log(CLR_R, err.Error())
+			}
+		}
+		if cache != "" {
+			if err := saveCache(cache, define); err != nil {
+				log(CLR_R, err.Error())
+			}
+		}
+		runHook(run, task, define.OnSuccess)
+	} else {
+		return configError("Task \"" + task + "\" Not Found")
+	}
+	return nil
+}
+
+// Check if current platform is in platforms of task
+func supportPlatform(names []string) bool {
+	for _, name := range names {
+		if strings.ToLower(name) == runtime.GOOS {
+			return true
+		}
+	}
+	return false
+}
+
+// Run commands of task by array order, failed command terminate the task
+func runCmds(run *buildRun, task string, define Task, daemon bool) error {
+	var retryDelay time.Duration
+	if define.RetryDelay != "" {
+		var err error
+		if retryDelay, err = time.ParseDuration(define.RetryDelay); err != nil {
+			return configError("Task \"" + task + "\" Retry Delay " + err.Error())
+		}
+	}
+	for idx, cmd := range define.Cmds {
+		// No command variant for current platform
+		if cmd.Cmd == "" && len(cmd.Parallel) == 0 && len(cmd.Pipe) == 0 {
+			continue
+		}
+		if run.canceled() {
+			return errCanceled
+		}
+		taskName := task + " [" + strconv.Itoa(idx) + "]"
+		// If command has - prefix, its failure not terminate the task
+		ignoreError := define.IgnoreErrors
+		if strings.HasPrefix(cmd.Cmd, "-") {
+			ignoreError = true
+			cmd.Cmd = cmd.Cmd[1:]
+		}
+		start := time.Now()
+		if commandRef(cmd.Cmd) == "" {
+			run.countCommand(task)
+		}
+		err := runCommand(run, task, idx, cmd, daemon)
+		// Rerun failed command if has retries
+		for retry := 1; retry <= define.Retries && err != nil && !run.canceled(); retry++ {
+			if _, ok := err.(configError); ok {
+				break
+			}
+			logTask(task, idx, "", CLR_G, taskName+" RETRY "+strconv.Itoa(retry)+"/"+strconv.Itoa(define.Retries))
+			time.Sleep(retryDelay)
+			err = runCommand(run, task, idx, cmd, daemon)
+		}
+		if _, ok := err.(configError); ok {
+			return err
+		}
+		elapsed := time.Since(start)
+		if commandRef(cmd.Cmd) == "" && !daemon {
+			run.recordCommand(task, idx, cmd, elapsed, err)
+		}
+		if err != nil && run.canceled() {
+			logTask(task, idx, "", CLR_G, taskName+" CANCELED after "+formatElapsed(elapsed))
+			return errCanceled
+		}
+		if err != nil && ignoreError {
+			logTask(task, idx, "", CLR_R, taskName+" Failed, Ignored: "+err.Error())
+			continue
+		}
+		if err != nil {
+			logTask(task, idx, "", CLR_G, taskName+describeTask(task)+" TERMINATED after "+formatElapsed(elapsed))
+			err := &taskError{task, err}
+			run.fail(err)
+			return err
+		}
+		// Daemon command keep running, elapsed time is meaningless
+		if daemon {
+			logTask(task, idx, "", CLR_G, taskName)
+			continue
+		}
+		run.record(taskName, elapsed)
+		logTask(task, idx, "", CLR_G, taskName+" done in "+formatElapsed(elapsed))
+	}
+	return nil
+}
+
+// Run hook task of task, hook result not affect the task
+func runHook(run *buildRun, task string, hook string) {
+	if hook == "" {
+		return
+	}
+	if ref := extractRef(hook); ref != "" {
+		hook = ref
+	}
+	if err := runTask(run, hook, false); err != nil {
+		if _, ok := err.(*taskError); !ok {
+			log(CLR_R, "Task \""+task+"\" Hook "+err.Error())
+		}
+	}
+}
+
+// Run deps of task in order, wait if dep is running by other task
+func runDeps(run *buildRun, task string) error {
+	order, err := depsOrder(task, nil, make(map[string]bool))
+	if err != nil {
+		return err
+	}
+	for _, dep := range order {
+		run.depsLock.Lock()
+		done, ok := run.deps[dep]
+		if !ok {
+			done = make(chan struct{})
+			run.deps[dep] = done
+		}
+		run.depsLock.Unlock()
+		if !ok {
+			err := runTask(run, dep, false)
+			run.depsLock.Lock()
+			run.depsErr[dep] = err
+			run.depsLock.Unlock()
+			close(done)
+		}
+		<-done
+		run.depsLock.Lock()
+		err := run.depsErr[dep]
+		run.depsLock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sort all deps of task in run order, stack keep the deps chain for detect
+// circular deps
+func depsOrder(task string, stack []string, visited map[string]bool) ([]string, error) {
+	for _, name := range stack {
+		if name == task {
+			chain := strings.Join(append(stack, task), " -> ")
+			return nil, configError("Task Circular Deps: " + chain)
+		}
+	}
+	define, ok := currentMap().Task[task]
+	if !ok {
+		return nil, configError("Task \"" + task + "\" Not Found")
+	}
+	var order []string
+	stack = append(stack, task)
+	for _, dep := range define.Deps {
+		if ref := extractRef(dep); ref != "" {
+			dep = ref
+		}
+		dep = resolveAlias(dep)
+		depOrder, err := depsOrder(dep, stack, visited)
+		if err != nil {
+			return nil, err
+		}
+		order = append(order, depOrder...)
+		if !visited[dep] {
+			visited[dep] = true
+			order = append(order, dep)
+		}
+	}
+	return order, nil
+}
+
+// Run task with max duration limit, terminate commands of the run and
+// return durationError when exceed
+func runTaskLimited(run *buildRun, tasks ...string) error {
+	parent, group := run.ctx, run.group
+	maxDuration := run.opts.MaxDuration
+	if maxDuration > 0 {
+		// Restore context after run, so caller see if run itself canceled
+		ctx, cancel := context.WithTimeout(parent, maxDuration)
+		defer func() {
+			cancel()
+			run.ctx, run.group = parent, group
+		}()
+		run.ctx, run.group = ctx, true
+	}
+	setLastTasks(tasks)
+	start := time.Now()
+	err := runTasks(run, tasks)
+	if err != nil && run.canceled() && parent.Err() == nil {
+		err = durationError("Build Exceed Max Duration " + maxDuration.String())
+	}
+	if run.opts.Profile {
+		run.printProfile()
+	}
+	run.finishSummary(time.Since(start))
+	if err != nil || run.failed != nil {
+		atomic.StoreInt32(&lastRunFailed, 1)
+	} else {
+		atomic.StoreInt32(&lastRunFailed, 0)
+	}
+	if err == nil && run.failed != nil {
+		err = run.failed
+	}
+	run.saveHistory(tasks, start, err)
+	notifyWebhooks(tasks, err, time.Since(start))
+	return err
+}
+
+// Run tasks in order, stop at first failed task; or run concurrently in
+// parallel mode
+func runTasks(run *buildRun, tasks []string) error {
+	if !run.opts.Parallel || run.opts.DryRun || len(tasks) == 1 {
+		for _, task := range tasks {
+			if err := runTask(run, task, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	errs := make([]error, len(tasks))
+	var group sync.WaitGroup
+	for idx, task := range tasks {
+		group.Add(1)
+		go func(idx int, task string) {
+			defer group.Done()
+			errs[idx] = runTask(run, task, false)
+		}(idx, task)
+	}
+	group.Wait()
+	// Config error take precedence over task error
+	var taskErr error
+	for _, err := range errs {
+		if _, ok := err.(configError); ok {
+			return err
+		}
+		if err != nil && taskErr == nil {
+			taskErr = err
+		}
+	}
+	return taskErr
+}
+
+// Log error of task run, shut down when fail fast or not keep watching
+func handleError(err error) {
+	if code, exit := errorExit(err); exit {
+		shutdown(code)
+	}
+}
+
+// Log error of task run, return exit code and true if should exit
+func errorExit(err error) (int, bool) {
+	if err == nil {
+		return 0, false
+	}
+	// Exceed max duration cancel only the run, keep watching if keep running
+	if _, ok := err.(durationError); ok {
+		exit := !keepRunning()
+		if exit {
+			stopTUI()
+		}
+		log(CLR_R, err.Error())
+		return 1, exit
+	}
+	// Terminated task already logged, exit with code of failed command
+	if err, ok := err.(*taskError); ok {
+		return err.exitCode(), exitOnError || !keepRunning()
+	}
+	exit := failFast || !keepRunning()
+	if exit {
+		stopTUI()
+		stopConsole()
+	}
+	log(CLR_R, err.Error())
+	return 1, exit
+}
+
+// Exit after first build run, not keep running for watch
+var once bool
+
+// Keep running after first build run, if watching, scheduling or serving
+// control or static files, and not run once
+func keepRunning() bool {
+	if once {
+		return false
+	}
+	config := currentMap()
+	return len(config.Watch) != 0 || len(config.Schedule) != 0 || serveAddr != "" || socketPath != "" || hasServers()
+}
+
+// Exit code requested by shutdown, received by main to exit process
+var exitCodes = make(chan int, 1)
+
+// Stop everything and request exit with code, only first code is kept
+func shutdown(code int) {
+	stopAll()
+	select {
+	case exitCodes <- code:
+	default:
+	}
+}
+
+// Stop watcher and terminate all running commands
+func stopAll() {
+	stopTUI()
+	stopConsole()
+	watcher.Close()
+	removeSession()
+	removeSocket()
+	stopServers()
+	processLock.Lock()
+	cmds := make(map[*exec.Cmd]chan struct{}, len(processes))
+	for cmd, done := range processes {
+		cmds[cmd] = done
+		killedProcesses[cmd] = true
+	}
+	processLock.Unlock()
+	var group sync.WaitGroup
+	for cmd, done := range cmds {
+		group.Add(1)
+		go func(cmd *exec.Cmd, done chan struct{}) {
+			defer group.Done()
+			terminateProcess(cmd, done)
+		}(cmd, done)
+	}
+	group.Wait()
+}
+
+// Terminate running daemon commands of task, wait them exit; state is
+// logged if task has daemons, return false if not
+func stopDaemons(task string, state string) bool {
+	processLock.Lock()
+	cmds := daemons[task]
+	delete(daemons, task)
+	processLock.Unlock()
+	if len(cmds) == 0 {
+		return false
+	}
+	logTask(task, -1, "", CLR_G, task+" "+state)
+	var group sync.WaitGroup
+	for cmd, done := range cmds {
+		group.Add(1)
+		go func(cmd *exec.Cmd, done chan struct{}) {
+			defer group.Done()
+			terminateProcess(cmd, done)
+		}(cmd, done)
+	}
+	group.Wait()
+	return true
+}
+
+// Terminate process by SIGTERM, then SIGKILL if not exit in grace period
+func terminateProcess(cmd *exec.Cmd, done chan struct{}) {
+	stopProcess(cmd)
+	select {
+	case <-done:
+	case <-time.After(killGrace):
+		killProcess(cmd)
+		<-done
+	}
+}
+
+// Run command or parallel command group, group fail if any command fail
+func runCommand(run *buildRun, task string, index int, command Command, daemon bool) error {
+	// No command variant for current platform
+	if command.Cmd == "" && len(command.Parallel) == 0 && len(command.Pipe) == 0 {
+		return nil
+	}
+	if len(command.Pipe) > 0 {
+		return runPipe(run, task, index, command, daemon)
+	}
+	if len(command.Parallel) == 0 {
+		return runCMD(run, task, index, command, daemon, false)
+	}
+	// Keep order of printed commands in dry run mode
+	if run.opts.DryRun {
+		for _, cmd := range command.Parallel {
+			if err := runCommand(run, task, index, cmd, daemon); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	errs := make([]error, len(command.Parallel))
+	var group sync.WaitGroup
+	for idx, cmd := range command.Parallel {
+		group.Add(1)
+		go func(idx int, cmd Command) {
+			defer group.Done()
+			errs[idx] = runCommand(run, task, index, cmd, daemon)
+		}(idx, cmd)
+	}
+	group.Wait()
+	// Config error take precedence over command error
+	var cmdErr error
+	for _, err := range errs {
+		if _, ok := err.(configError); ok {
+			return err
+		}
+		if err != nil && cmdErr == nil {
+			cmdErr = err
+		}
+	}
+	return cmdErr
+}
+
+// Get exec command run the command string by shell of system
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("/bin/sh", "-c", command)
+}
+
+// Describe working directory of command for log, empty if not specified
+func inDir(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return " (in " + dir + ")"
+}
+
+// Run command defined in task, output is hidden in quiet mode
+func runCMD(run *buildRun, task string, index int, define Command, daemon bool, quiet bool) error {
+	command := define.Cmd
+	// Run task if command is task name, terminated task not break caller
+	if taskName := extractRef(command); taskName != "" && !strings.HasPrefix(taskName, "env:") {
+		if err := runTask(run, taskName, daemon); err != nil {
+			if _, ok := err.(*taskError); !ok {
+				return err
+			}
+		}
+		return nil
+	}
+	// Parse variable in command
+	command, err := run.parseVariable(command)
+	if err != nil {
+		return err
+	}
+	dir, env, timeout, err := commandSetup(run, task, define, daemon)
+	if err != nil {
+		return err
+	}
+	// Print command only in dry run mode
+	if run.opts.DryRun {
+		command += inDir(dir)
+		if len(env) > 0 {
+			command += " (env " + strings.Join(env, " ") + ")"
+		}
+		if daemon {
+			command += " (non-block)"
+		} else if quiet {
+			command += " (condition)"
+		}
+		logTask(task, index, task, CLR_B, command)
+		return nil
+	}
+	// Prepare exec command, run by shell in container or on remote machine
+	// of task, by plugin if command has scheme prefix, or by shell
+	var cmd *exec.Cmd
+	if container := currentMap().Task[task].Container; container != nil {
+		if cmd, err = containerCommand(run, container, command, dir, env); err != nil {
+			return err
+		}
+		// Dir of task is mapped to working directory in container
+		dir = ""
+	} else if remote := currentMap().Task[task].Remote; remote != nil {
+		if cmd, err = remoteCommand(run, remote, command, dir, env); err != nil {
+			return err
+		}
+		// Dir of task is on remote machine
+		dir = ""
+	} else {
+		handler, plugin, args, ok, err := findPlugin(command)
+		if err != nil {
+			return err
+		}
+		if handler != nil {
+			if verboseLog(run, task) {
+				logTask(task, index, outputPrefix(task), CLR_B, "+ builtin "+quoteArgs(append(strings.Fields(command)[:1], args...))+inDir(dir))
+			}
+			return runBuiltin(run, task, index, handler, args, dir, append(taskEnv(task), env...), timeout, daemon, quiet)
+		}
+		if ok {
+			cmd = exec.Command(plugin, args...)
+		} else if cmd, err = taskCommand(run, task, define.Cmd, command); err != nil {
+			return err
+		}
+	}
+	cmd.Env = append(taskEnv(task), env...)
+	cmd.Dir = dir
+	if verboseLog(run, task) {
+		logTask(task, index, outputPrefix(task), CLR_B, "+ "+commandLine(cmd.Args)+inDir(dir))
+	}
+	// Run in its own process group (job object on windows), to kill its
+	// children when stop daemon, timeout or cancel; interactive one must
+	// stay in foreground group to read terminal, and one under pty run in
+	// its own session
+	interactive := currentMap().Task[task].Interactive && !quiet
+	usePty := (run.opts.Pty || currentMap().Task[task].Pty) && !interactive && !quiet
+	grouped := (daemon || timeout > 0 || run.group) && !interactive && !usePty
+	if grouped {
+		setProcessGroup(cmd)
+	}
+	// Keep recent output of daemon to replay when it exit unexpectedly
+	var ring *outputRing
+	if daemon {
+		ring = newOutputRing(run.opts.DaemonBuffer)
+	}
+	// Connect interactive task to terminal, or print stdout and stderr of
+	// process
+	var output sync.WaitGroup
+	var stdin *os.File
+	var release func()
+	if interactive {
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if stdin, release, err = interactiveStdin(); err != nil {
+			return err
+		}
+		cmd.Stdin = stdin
+	} else if usePty {
+		// Stdout and stderr are merged in pty, slave is closed once started
+		var master *os.File
+		if master, stdin, err = startPty(cmd); err != nil {
+			return err
+		}
+		out := bufio.NewScanner(master)
+		output.Add(1)
+		go func() {
+			defer output.Done()
+			for out.Scan() {
+				ring.add(CLR_W, strings.TrimSuffix(out.Text(), "\r"))
+				logCommand(task, index, CLR_W, strings.TrimSuffix(out.Text(), "\r"))
+			}
+			master.Close()
+		}()
+	} else {
+		stdout, _ := cmd.StdoutPipe()
+		stderr, _ := cmd.StderrPipe()
+		out := bufio.NewScanner(stdout)
+		errOut := bufio.NewScanner(stderr)
+		output.Add(2)
+		// Print stdout
+		go func() {
+			defer output.Done()
+			for out.Scan() {
+				ring.add(CLR_W, out.Text())
+				if !quiet {
+					logCommand(task, index, CLR_W, out.Text())
+				}
+			}
+		}()
+		// Print stdin
+		go func() {
+			defer output.Done()
+			for errOut.Scan() {
+				ring.add(CLR_R, errOut.Text())
+				if !quiet {
+					logCommand(task, index, CLR_R, errOut.Text())
+				}
+			}
+		}()
+	}
+	// Exec command, wait all output printed before process finish
+	execute := func() error {
+		startCMD()
+		defer finishCMD()
+		if release != nil {
+			defer release()
+		}
+		err := cmd.Start()
+		if stdin != nil && stdin != os.Stdin {
+			stdin.Close()
+		}
+		if err != nil {
+			return err
+		}
+		if grouped {
+			attachProcess(cmd)
+			defer releaseProcess(cmd)
+		}
+		done := make(chan struct{})
+		processLock.Lock()
+		processes[cmd] = done
+		if daemon {
+			if daemons[task] == nil {
+				daemons[task] = make(map[*exec.Cmd]chan struct{})
+			}
+			daemons[task][cmd] = done
+			daemonStatus[cmd] = DaemonStatus{task, cmd.Process.Pid, command, time.Now()}
+		}
+		processLock.Unlock()
+		if daemon {
+			startSession()
+		} else {
+			cancelProcesses(run, []*exec.Cmd{cmd}, done)
+		}
+		var exitErr error
+		defer func() {
+			processLock.Lock()
+			// Daemon not removed by stop and not killed is unexpected exit
+			_, unexpected := daemons[task][cmd]
+			unexpected = unexpected && !killedProcesses[cmd]
+			delete(processes, cmd)
+			delete(daemons[task], cmd)
+			delete(daemonStatus, cmd)
+			delete(killedProcesses, cmd)
+			processLock.Unlock()
+			close(done)
+			if daemon && unexpected {
+				replayDaemon(task, index, ring, exitErr)
+			}
+		}()
+		// Terminate command if timeout
+		var timedOut int32
+		if timeout > 0 {
+			timer := time.AfterFunc(timeout, func() {
+				atomic.StoreInt32(&timedOut, 1)
+				logTask(task, index, outputPrefix(task), CLR_R, "Command Timeout After "+timeout.String())
+				terminateProcess(cmd, done)
+			})
+			defer timer.Stop()
+		}
+		output.Wait()
+		err = cmd.Wait()
+		if atomic.LoadInt32(&timedOut) == 1 {
+			err = errors.New("Timeout After " + timeout.String())
+		} else if err != nil && !daemon && run.canceled() {
+			err = errCanceled
+		}
+		exitErr = err
+		return err
+	}
+	if daemon {
+		// Run in non-block mode
+		go execute()
+		return nil
+	}
+	return execute()
+}
+
+// Get working directory, environment and timeout of command in task;
+// --timeout not apply to daemon, only timeout of task does
+func commandSetup(run *buildRun, task string, define Command, daemon bool) (dir string, env []string, timeout time.Duration, err error) {
+	config := currentMap()
+	// Get working directory of task
+	if dir = config.Task[task].Dir; dir != "" {
+		if dir, err = run.parseVariable(dir); err != nil {
+			return "", nil, 0, err
+		}
+		dir = expandPath(dir)
+	}
+	// Get timeout of command
+	if !daemon {
+		timeout = run.opts.Timeout
+	}
+	if value := config.Task[task].Timeout; value != "" {
+		if timeout, err = time.ParseDuration(value); err != nil {
+			return "", nil, 0, configError("Task \"" + task + "\" Timeout " + err.Error())
+		}
+	}
+	// Environment of matrix combination, task and command, later one take
+	// precedence
+	for _, name := range sortedKeys(run.matrix) {
+		env = append(env, name+"="+run.matrix[name])
+	}
+	for _, vars := range []map[string]string{config.Task[task].Env, define.Env} {
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value, err := run.parseVariable(vars[name])
+			if err != nil {
+				return "", nil, 0, err
+			}
+			env = append(env, name+"="+value)
+		}
+	}
+	return dir, env, timeout, nil
+}
+
+// Unmarshal TOML format config
+func unmarshalTOMLConfig(content []byte, define interface{}) error {
+	_, err := toml.Decode(string(content), define)
+	return err
+}
+
+// Parse config file content into build map, format detect by extension,
+// if extension is unknown, detect by content and try both format
+func parseConfig(configFile string, content []byte, define *BuildMap) error {
+	switch strings.ToLower(filepath.Ext(configFile)) {
+	case ".json":
+		return json.Unmarshal(content, define)
+	case ".yml", ".yaml":
+		return yaml.Unmarshal(content, define)
+	case ".toml":
+		return unmarshalTOMLConfig(content, define)
+	}
+	parsers := []func([]byte, interface{}) error{yaml.Unmarshal, json.Unmarshal, unmarshalTOMLConfig}
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")) {
+		parsers[0], parsers[1] = parsers[1], parsers[0]
+	}
+	// Report error of the format content looks like
+	var firstErr error
+	for _, parser := range parsers {
+		*define = BuildMap{}
+		err := parser(content, define)
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Load config file and merge its include files, include path is relative
+// to the config file, loading keep the include chain for detect circular
+func loadConfig(configFile string, loading []string) (BuildMap, error) {
+	var define BuildMap
+	configFile = filepath.Clean(configFile)
+	for _, file := range loading {
+		if file == configFile {
+			chain := strings.Join(append(loading, configFile), " -> ")
+			return define, fmt.Errorf("Circular Include: %s", chain)
+		}
+	}
+	content, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return define, err
+	}
+	loadingFiles = append(loadingFiles, configFile)
+	if err := parseConfig(configFile, content, &define); err != nil {
+		return define, fmt.Errorf("%s: %s", configFile, err)
+	}
+	if len(define.Include) == 0 && len(define.Namespaces) == 0 {
+		return define, nil
+	}
+	var merged BuildMap
+	loading = append(loading, configFile)
+	for _, include := range define.Include {
+		include = expandPath(include)
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(configFile), include)
+		}
+		included, err := loadConfig(include, loading)
+		if err != nil {
+			return define, err
+		}
+		mergeConfig(&merged, included)
+	}
+	for _, name := range sortedKeys(define.Namespaces) {
+		included, err := loadNamespace(configFile, name, define.Namespaces[name], loading)
+		if err != nil {
+			return define, err
+		}
+		mergeConfig(&merged, included)
+	}
+	mergeConfig(&merged, define)
+	return merged, nil
+}
+
+// Get user config file, build.yml in build.go directory of XDG_CONFIG_HOME
+// or ~/.config
+func userConfigFile() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "build.go", "build.yml")
+}
+
+// Whether skip user config
+var noUserConfig bool
+
+// Load config file, with variables and tasks of user config merged under
+// it if exists
+func loadProjectConfig(configFile string) (BuildMap, error) {
+	define, err := loadConfig(configFile, nil)
+	if err != nil || noUserConfig {
+		return define, err
+	}
+	file := userConfigFile()
+	if _, err := os.Stat(file); file == "" || err != nil {
+		return define, nil
+	}
+	user, err := loadConfig(file, nil)
+	if err != nil {
+		return define, err
+	}
+	merged := BuildMap{Variable: user.Variable, Task: user.Task}
+	mergeConfig(&merged, define)
+	return merged, nil
+}
+
+// Merge config into another, value in src override dst
+func mergeConfig(dst *BuildMap, src BuildMap) {
+	if dst.Variable == nil {
+		dst.Variable = make(map[string]string)
+	}
+	if dst.Task == nil {
+		dst.Task = make(map[string]Task)
+	}
+	if dst.Watch == nil {
+		dst.Watch = make(map[string]Watch)
+	}
+	for name, value := range src.Variable {
+		dst.Variable[name] = value
+	}
+	for name, task := range src.Task {
+		dst.Task[name] = task
+	}
+	for pattern, watch := range src.Watch {
+		dst.Watch[pattern] = watch
+	}
+	if len(src.Schedule) > 0 && dst.Schedule == nil {
+		dst.Schedule = make(map[string]string)
+	}
+	for expr, task := range src.Schedule {
+		dst.Schedule[expr] = task
+	}
+	if len(src.Prompt) > 0 && dst.Prompt == nil {
+		dst.Prompt = make(map[string]Prompt)
+	}
+	for name, define := range src.Prompt {
+		dst.Prompt[name] = define
+	}
+	if len(src.Alias) > 0 && dst.Alias == nil {
+		dst.Alias = make(map[string]string)
+	}
+	for alias, task := range src.Alias {
+		dst.Alias[alias] = task
+	}
+	dst.Ignore = append(dst.Ignore, src.Ignore...)
+	dst.LiveReload = dst.LiveReload || src.LiveReload
+	dst.Notify = append(dst.Notify, src.Notify...)
+	dst.RequiredVars = append(dst.RequiredVars, src.RequiredVars...)
+}
+
+// Use config in other format if default config not exist, then search
+// parent directories like git does for .git
+func findConfigFile(path string) string {
+	if path != "build.yml" {
+		return path
+	}
+	dir := "."
+	for {
+		for _, file := range []string{"build.yml", "build.yaml", "build.json", "build.toml"} {
+			if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+				return filepath.Join(dir, file)
+			}
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil || filepath.Dir(abs) == abs {
+			return path
+		}
+		dir = filepath.Join(dir, "..")
+	}
+}
+
+// Change working directory to directory of config found in parent, so
+// tasks run relative to it, return config path in new directory
+func enterConfigDir(path string) string {
+	dir := filepath.Dir(path)
+	if dir == "." || !strings.HasPrefix(filepath.ToSlash(dir), "..") {
+		return path
+	}
+	if err := os.Chdir(dir); err != nil {
+		return path
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		log(CLR_W, "Using Config "+filepath.Join(cwd, filepath.Base(path)))
+	}
+	return filepath.Base(path)
+}
+
+// Config file, env file, variables and arguments in command line, used when
+// load or reload build map
+var configFile string
+var envFile string
+var varOverrides []string
+var extraArgs []string
+
+// Load build map from config file and its include files, with env file and
+// variables in command line; keep current build map if failed
+func loadBuildMap() error {
+	define, err := readBuildMap()
+	if err != nil {
+		return err
+	}
+	applyBuildMap(define)
+	return nil
+}
+
+// Read and resolve build map, current build map is not changed
+func readBuildMap() (BuildMap, error) {
+	loadingFiles = nil
+	define, err := loadProjectConfig(configFile)
+	if err != nil {
+		return define, errors.New("Config " + err.Error())
+	}
+	if define.Variable == nil {
+		define.Variable = make(map[string]string)
+	}
+	// Load env file, default .env is optional
+	if envFile != "" {
+		if err := loadEnvFile(expandPath(envFile), &define); err != nil {
+			return define, err
+		}
+	} else if _, err := os.Stat(".env"); err == nil {
+		if err := loadEnvFile(".env", &define); err != nil {
+			return define, err
+		}
+	}
+	// Override variables before prehandle, so nested variable use them
+	for _, pair := range varOverrides {
+		idx := strings.Index(pair, "=")
+		if idx <= 0 {
+			return define, errors.New("Variable \"" + pair + "\" Invalid, Expect KEY=VALUE")
+		}
+		define.Variable[pair[:idx]] = pair[idx+1:]
+	}
+	if _, ok := define.Variable["ARGS"]; !ok || len(extraArgs) > 0 {
+		define.Variable["ARGS"] = quoteArgs(extraArgs)
+	}
+	// Prehandle for config file
+	// Support nest variable
+	if missing := missingVars(nil, define.RequiredVars, define.lookupOrAsk); len(missing) > 0 {
+		return define, configError("Required Variables Not Set: " + strings.Join(missing, ", "))
+	}
+	if err := define.resolveVariables(); err != nil {
+		return define, err
+	}
+	if _, err := define.parseSchedules(); err != nil {
+		return define, errors.New("Config " + err.Error())
+	}
+	return define, nil
+}
+
+// Use read build map, with its schedules, ignore file and config files
+func applyBuildMap(define BuildMap) {
+	setBuildMap(define)
+	parsed, _ := define.parseSchedules()
+	setSchedules(parsed)
+	if err := loadIgnoreFile(); err != nil {
+		log(CLR_R, "Ignore File "+err.Error())
+	}
+	setConfigFiles(loadingFiles)
+}
+
+// Reload build map and restart watch, keep current config if new config is
+// invalid or has problems found by validate
+func reloadConfig() {
+	define, err := readBuildMap()
+	if err != nil {
+		log(CLR_R, err.Error())
+		return
+	}
+	if problems := define.validateConfig(); len(problems) > 0 {
+		for _, problem := range problems {
+			log(CLR_R, problem)
+		}
+		log(CLR_R, "Config Not Reloaded, "+strconv.Itoa(len(problems))+" Problems Found")
+		return
+	}
+	applyBuildMap(define)
+	if err := restartWatch(); err != nil {
+		log(CLR_R, err.Error())
+	}
+	log(CLR_G, "Config Reloaded")
+}
+
+// Print all tasks with command count, watch patterns and schedules with task
+func listTasks() {
+	config := currentMap()
+	names := make([]string, 0, len(config.Task))
+	width := 0
+	for name := range config.Task {
+		names = append(names, name)
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	sort.Strings(names)
+	fmt.Println("Tasks:")
+	for _, name := range names {
+		count := len(config.Task[name].Cmds)
+		unit := "commands"
+		if count == 1 {
+			unit = "command"
+		}
+		line := fmt.Sprintf("    %-*s  %d %s", width, name, count, unit)
+		if desc := config.Task[name].Desc; desc != "" {
+			line = fmt.Sprintf("%-*s  %s", width+18, line, desc)
+		}
+		if aliases := taskAliases(name); len(aliases) > 0 {
+			line += " (alias " + strings.Join(aliases, ", ") + ")"
+		}
+		fmt.Println(line)
+	}
+	listWatches()
+	listSchedules()
+}
+
+// Print watch patterns with task, in order of pattern
+func listWatches() {
+	if len(currentMap().Watch) == 0 {
+		return
+	}
+	patterns := make([]string, 0, len(currentMap().Watch))
+	for pattern := range currentMap().Watch {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	fmt.Println("Watches:")
+	for _, pattern := range patterns {
+		path := pattern
+		if parsed, err := parseVariable(pattern); err == nil {
+			path = expandPath(parsed)
+		}
+		fmt.Printf("    %s -> %s\n", path, strings.Join(currentMap().Watch[pattern].Task, ", "))
+	}
+}
+
+// Print schedules with task, in order of expression
+func listSchedules() {
+	if len(currentMap().Schedule) == 0 {
+		return
+	}
+	exprs := make([]string, 0, len(currentMap().Schedule))
+	for expr := range currentMap().Schedule {
+		exprs = append(exprs, expr)
+	}
+	sort.Strings(exprs)
+	fmt.Println("Schedules:")
+	for _, expr := range exprs {
+		fmt.Printf("    %s -> %s\n", expr, currentMap().Schedule[expr])
+	}
+}
+
+// Quote arguments for shell, keep simple argument as it is
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for idx, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n\"'`$\\|&;<>()*?[]{}~#!") {
+			quoted[idx] = arg
+		} else if runtime.GOOS == "windows" {
+			quoted[idx] = "\"" + strings.Replace(arg, "\"", "\\\"", -1) + "\""
+		} else {
+			quoted[idx] = "'" + strings.Replace(arg, "'", "'\\''", -1) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Init some global variable
+func init() {
+	watcher, _ = fsnotify.NewWatcher()
+	varRegex = regexp.MustCompile("\\${(env:)?[A-Za-z0-9_-]+(:[A-Za-z0-9_][A-Za-z0-9_-]*)*(:-[^}]*)?}")
+	watchDir = make(map[string]bool)
+	noColor = os.Getenv("NO_COLOR") != "" || !isTerminal(os.Stdout) || !enableColor(os.Stdout)
+}
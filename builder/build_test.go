@@ -0,0 +1,78 @@
+package builder
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestResolveVariables(t *testing.T) {
+	t.Setenv("BUILD_GO_TEST", "env")
+	nonInteractive = true
+	defer func() { nonInteractive = false }()
+	tests := []struct {
+		name     string
+		variable map[string]string
+		want     map[string]string
+	}{
+		{
+			"plain",
+			map[string]string{"a": "x"},
+			map[string]string{"a": "x"},
+		},
+		{
+			"nested",
+			map[string]string{"a": "${b}/a", "b": "${c}/b", "c": "c"},
+			map[string]string{"a": "c/b/a", "b": "c/b", "c": "c"},
+		},
+		{
+			"environment",
+			map[string]string{"a": "${env:BUILD_GO_TEST}", "b": "${BUILD_GO_TEST}"},
+			map[string]string{"a": "env", "b": "env"},
+		},
+		{
+			"default",
+			map[string]string{"a": "${BUILD_GO_MISSING:-x}", "b": "${c:-y}", "c": ""},
+			map[string]string{"a": "x", "b": "y", "c": ""},
+		},
+		{
+			"built-in",
+			map[string]string{"a": "${OS}", "b": "${FILE}"},
+			map[string]string{"a": runtime.GOOS, "b": "${FILE}"},
+		},
+		{
+			"override built-in",
+			map[string]string{"a": "${OS}", "OS": "os"},
+			map[string]string{"a": "os", "OS": "os"},
+		},
+	}
+	for _, test := range tests {
+		config := BuildMap{Variable: test.variable}
+		if err := config.resolveVariables(); err != nil {
+			t.Errorf("%s: resolveVariables error: %s", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(config.Variable, test.want) {
+			t.Errorf("%s: resolveVariables = %v, want %v", test.name, config.Variable, test.want)
+		}
+	}
+}
+
+func TestResolveVariablesInvalid(t *testing.T) {
+	nonInteractive = true
+	defer func() { nonInteractive = false }()
+	tests := []struct {
+		name     string
+		variable map[string]string
+	}{
+		{"circular", map[string]string{"a": "${b}", "b": "${a}"}},
+		{"self", map[string]string{"a": "${a}"}},
+		{"missing", map[string]string{"a": "${BUILD_GO_MISSING}"}},
+	}
+	for _, test := range tests {
+		config := BuildMap{Variable: test.variable}
+		if err := config.resolveVariables(); err == nil {
+			t.Errorf("%s: resolveVariables expect error", test.name)
+		}
+	}
+}
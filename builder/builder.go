@@ -0,0 +1,471 @@
+// Package builder load Build.go config, run tasks and watch file change, it
+// could be embedded in other tools; the build.go command is a thin wrapper
+// of it. Loaded config, log output and running commands are state of the
+// process, so load one config at a time; options of runs and watch could be
+// set on Runner and Watcher. The library never exit the process, exit code
+// is returned by HandleError or received from Exited.
+package builder
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Options of build run, set by Configure before load config as default
+// of process; Runner and Watcher could have their own options for their
+// runs, log and config options of them are ignored
+type Options struct {
+	// Env file to load, default .env if exists
+	EnvFile string
+	// Variables override config, as KEY=VALUE
+	Vars []string
+	// Not merge variables and tasks of user config ~/.config/build.go/build.yml
+	NoUserConfig bool
+	// Arguments pass to commands as ${ARGS}
+	Args []string
+	// Logger receive log entries instead of print to stdout
+	Logger Logger
+	// Print log without color, also disabled by NO_COLOR or non terminal
+	NoColor bool
+	// Hide detail log when running build
+	Silent bool
+	// Log format, text or json, default text
+	LogFormat string
+	// Write newline delimited JSON events to stdout instead of log, like
+	// task_started, command_output, task_finished and watch_triggered
+	Porcelain bool
+	// Also write all output to file, rotate when exceed LogMaxSize bytes
+	LogFile    string
+	LogMaxSize int64
+	// Prefix log lines with time
+	Timestamps bool
+	// Truncate output line longer than bytes, and suppress output lines of
+	// each command exceed count per second; 0 mean no limit
+	MaxLineLength int
+	MaxLineRate   int
+	// Print elapsed time of tasks and commands after build
+	Profile bool
+	// Print summary of tasks, commands and failures after build
+	Summary bool
+	// Append each build run to .build/history.jsonl, for report subcommand
+	Record bool
+	// Keep log when watched file change again, same as clear policy never
+	Keep bool
+	// Clear log before watch triggered run: never, on-change, or on-error
+	// only if last run failed; default on-change, or never if keep
+	Clear string
+	// Max duration of a build run, and of each command
+	MaxDuration time.Duration
+	Timeout     time.Duration
+	// Exit on undefined task or variable even in watch mode
+	FailFast bool
+	// Exit with code of failed command even in watch mode
+	ExitOnError bool
+	// Print expanded commands in order without execute
+	DryRun bool
+	// Only list tasks, $(command) of variables are not run like dry run
+	List bool
+	// Print command line with shell before execute each command
+	Verbose bool
+	// Answer yes to confirm of tasks
+	Yes bool
+	// Fail on variable without value instead of ask
+	NonInteractive bool
+	// Run commands under pseudo terminal, only on linux and darwin
+	Pty bool
+	// Kill daemon commands of task before run the task again
+	Restart bool
+	// Lines of daemon output replayed when daemon exit unexpectedly, 0
+	// disable replay
+	DaemonBuffer int
+	// Run tasks of a build run concurrently
+	Parallel bool
+	// Send desktop notification when watch triggered task finish
+	Notify bool
+	// Coalesce changes of same watch pattern in window
+	Debounce time.Duration
+	// Collect file changes in watch window, run each triggered task once
+	WatchTriggerAll bool
+	WatchWindow     time.Duration
+	// Watch trigger while task running: queue, drop or cancel, default
+	// queue
+	WatchPolicy string
+	// Max watch triggered runs at the same time, 0 mean no limit
+	MaxParallel int
+	// Scan watched files on interval instead of file system events
+	Poll time.Duration
+	// Exit after first build run, even if has watches, schedules or servers
+	Once bool
+}
+
+// Receive log entries, for embedding tools to handle log by themselves
+type Logger interface {
+	Log(entry LogEntry)
+}
+
+// Log entry, type is LOG, ERR, RUN or CMD; index is command index in task
+// or -1
+type LogEntry struct {
+	Time    time.Time
+	Type    string
+	Task    string
+	Index   int
+	Message string
+}
+
+// Custom logger set by options
+var logger Logger
+
+// Check options and fill default values
+func (options *Options) normalize() error {
+	if options.LogFormat == "" {
+		options.LogFormat = "text"
+	}
+	if options.LogFormat != "text" && options.LogFormat != "json" {
+		return errors.New("Log Format \"" + options.LogFormat + "\" Not Supported")
+	}
+	if options.WatchPolicy == "" {
+		options.WatchPolicy = "queue"
+	}
+	if !containsString(watchPolicies, options.WatchPolicy) {
+		return errors.New("Watch Policy \"" + options.WatchPolicy + "\" Not Supported")
+	}
+	if options.Clear == "" {
+		options.Clear = "on-change"
+		if options.Keep {
+			options.Clear = "never"
+		}
+	}
+	if options.Clear != "never" && options.Clear != "on-change" && options.Clear != "on-error" {
+		return errors.New("Clear Policy \"" + options.Clear + "\" Not Supported")
+	}
+	if options.WatchWindow <= 0 {
+		options.WatchWindow = 200 * time.Millisecond
+	}
+	return nil
+}
+
+// Default options of process, used by runs of Runner and Watcher without
+// options
+var defaultOptions = Options{LogFormat: "text", WatchPolicy: "queue", Clear: "on-change", WatchWindow: 200 * time.Millisecond}
+
+// Apply options of process, check log format and watch policy; relative
+// path of env file and log file is resolved to working directory now, not
+// the config directory LoadConfig change to
+func Configure(options Options) error {
+	if err := options.normalize(); err != nil {
+		return err
+	}
+	for _, path := range []*string{&options.EnvFile, &options.LogFile} {
+		if *path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(expandPath(*path))
+		if err != nil {
+			return err
+		}
+		*path = abs
+	}
+	if options.LogFile != "" {
+		var err error
+		if logOutput, err = openLogFile(options.LogFile, options.LogMaxSize); err != nil {
+			return err
+		}
+	}
+	defaultOptions = options
+	setWatchOptions(&defaultOptions)
+	envFile = options.EnvFile
+	varOverrides = options.Vars
+	noUserConfig = options.NoUserConfig
+	extraArgs = options.Args
+	logger = options.Logger
+	noColor = noColor || options.NoColor
+	noDetailLog = options.Silent
+	logFormat = options.LogFormat
+	porcelain = options.Porcelain
+	timestamps = options.Timestamps
+	maxLineLength = options.MaxLineLength
+	maxLineRate = options.MaxLineRate
+	failFast = options.FailFast
+	exitOnError = options.ExitOnError
+	listOnly = options.List || options.DryRun
+	nonInteractive = options.NonInteractive
+	once = options.Once
+	return nil
+}
+
+// Get checked copy of options, or default options if nil
+func resolveOptions(options *Options) (*Options, error) {
+	if options == nil {
+		return &defaultOptions, nil
+	}
+	copied := *options
+	if err := copied.normalize(); err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
+
+// Load config file and its include files, with env file and variables of
+// options. Working directory of process is changed to directory of config,
+// which may be found in parent directory, so relative paths of config and
+// commands are resolved to it. It is used by later runs and watch
+func LoadConfig(path string) (*BuildMap, error) {
+	configFile = enterConfigDir(FindConfigFile(path))
+	if err := loadBuildMap(); err != nil {
+		return nil, err
+	}
+	define := currentMap()
+	return &define, nil
+}
+
+// Read config file and its include files, with user config, without
+// resolve variables
+func ReadConfig(path string) (BuildMap, error) {
+	return loadProjectConfig(enterConfigDir(FindConfigFile(path)))
+}
+
+// Change working directory to project of config, found in parent
+// directories like LoadConfig does, so .build of project is used by
+// subcommands run in nested directory
+func EnterProjectDir(path string) {
+	enterConfigDir(FindConfigFile(path))
+}
+
+// Check project config found like LoadConfig define task or alias of name,
+// without change working directory, false if config not readable
+func HasTask(path, name string) bool {
+	define, err := loadConfig(FindConfigFile(path), nil)
+	loadingFiles = nil
+	if err != nil {
+		return false
+	}
+	if _, ok := define.Task[name]; ok {
+		return true
+	}
+	for _, task := range define.Task {
+		for _, alias := range task.Aliases {
+			if alias == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Get config file path, expand ~ and fall back to build.yaml, build.json
+// or build.toml if default build.yml not exists, searched in parent
+// directories too
+func FindConfigFile(path string) string {
+	return findConfigFile(expandPath(path))
+}
+
+// Expand ~ to home directory in path
+func ExpandPath(path string) string {
+	return expandPath(path)
+}
+
+// Run tasks of loaded config
+type Runner struct {
+	// Changed file trigger the run as ${FILE}, empty for first run
+	File string
+	// Options of the run like dry run, parallel and timeout, nil to use
+	// options of Configure
+	Options *Options
+}
+
+// Create build run with options of runner
+func (runner *Runner) newRun(ctx context.Context) (*buildRun, error) {
+	opts, err := resolveOptions(runner.Options)
+	if err != nil {
+		return nil, err
+	}
+	run := newBuildRun(runner.File)
+	run.opts = opts
+	run.ctx, run.group = ctx, ctx.Done() != nil
+	return run, nil
+}
+
+// Run tasks in order, or concurrently in parallel mode; running commands
+// of the run are terminated when context is done, daemons keep running
+func (runner *Runner) RunTask(ctx context.Context, tasks ...string) error {
+	run, err := runner.newRun(ctx)
+	if err != nil {
+		return err
+	}
+	if run.opts.DryRun {
+		err = runTasks(run, tasks)
+	} else {
+		err = runTaskLimited(run, tasks...)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Run command string with variables, environment and working directory of
+// task, or of config if task is empty; running command is terminated when
+// context is done
+func (runner *Runner) Exec(ctx context.Context, command string, task string) error {
+	task = resolveAlias(task)
+	if _, ok := currentMap().Task[task]; task != "" && !ok {
+		return configError("Task \"" + task + "\" Not Found")
+	}
+	run, err := runner.newRun(ctx)
+	if err != nil {
+		return err
+	}
+	err = runCMD(run, task, -1, Command{Cmd: command}, false, false)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Watch files, schedules and LiveReload of loaded config
+type Watcher struct {
+	// Options of watch like debounce and policy, also of triggered runs;
+	// nil to use options of Configure
+	Options *Options
+}
+
+// Start to watch file change and run schedules in background, stop when
+// context is done; one watcher could be started in process
+func (w *Watcher) Start(ctx context.Context) error {
+	opts, err := resolveOptions(w.Options)
+	if err != nil {
+		return err
+	}
+	setWatchOptions(opts)
+	if opts.Poll > 0 {
+		w.startExtras(ctx)
+		startPoll(ctx, opts.Poll)
+		return nil
+	}
+	if err := addWatches(); err != nil {
+		return err
+	}
+	// Listen watched file change event
+	go func() {
+		for {
+			select {
+			case event := <-watcher.Events:
+				// Handle when file change
+				handleWatch(event)
+			case err := <-watcher.Errors:
+				log(CLR_R, err.Error())
+			case <-ctx.Done():
+				for _, dir := range resetWatchDirs() {
+					watcher.Remove(dir)
+				}
+				return
+			}
+		}
+	}()
+	w.startExtras(ctx)
+	return nil
+}
+
+// Start LiveReload and schedules along with watch
+func (w *Watcher) startExtras(ctx context.Context) {
+	if config := currentMap(); config.LiveReload && len(config.Watch) != 0 {
+		startLiveReload()
+	}
+	startSchedule(ctx)
+}
+
+// Print log, color is one of CLR_W (LOG), CLR_R (ERR), CLR_G (RUN) and
+// CLR_B (CMD)
+func Log(color string, info interface{}) {
+	log(color, info)
+}
+
+// Log error of task run, return exit code and true if should exit, when
+// fail fast, exit on error or not keep watching
+func HandleError(err error) (int, bool) {
+	return errorExit(err)
+}
+
+// Check if keep running after first build run, if watching, scheduling or
+// serving
+func KeepRunning() bool {
+	return keepRunning()
+}
+
+// Stop watcher, servers and terminate all running commands, without exit
+func Shutdown() {
+	stopAll()
+}
+
+// Receive exit code requested by console quit, fail fast or exit on error
+// in watch triggered runs; watcher and commands are already stopped when
+// received
+func Exited() <-chan int {
+	return exitCodes
+}
+
+// Print all tasks, watches and schedules of loaded config
+func ListTasks() {
+	listTasks()
+}
+
+// Reload config and restart watch, keep current config if invalid
+func ReloadConfig() {
+	reloadConfig()
+}
+
+// Run tasks in background, or tasks of last build run if not specified
+func Rerun(tasks []string) {
+	rerunTasks(tasks)
+}
+
+// Serve HTTP control on address, POST /task/{name} and GET /status;
+// requests need Bearer token of session file
+func ServeControl(addr string) {
+	serveAddr = addr
+	startServe(addr)
+}
+
+// Listen control socket on path for listing and running tasks, streaming
+// events and stopping daemons, a JSON request per line
+func ServeSocket(path string) error {
+	socketPath = path
+	return startSocket(path)
+}
+
+// Print summary of last finished build run
+func PrintSummary() {
+	printLastSummary()
+}
+
+// Check running build.go instance of current directory by policy: error,
+// wait, forward or allow; return true if tasks are forwarded to it
+func CheckInstance(policy string, tasks []string) (bool, error) {
+	return checkInstance(policy, tasks)
+}
+
+// Write session file of this instance, so other instances could find it
+// and forward tasks to it
+func StartSession() {
+	startSession()
+}
+
+// Show TUI dashboard of tasks and their logs while watching, instead of
+// console; linux and darwin only
+func StartTUI() error {
+	return startTUI()
+}
+
+// Read console input while watching
+func StartConsole() {
+	startConsole()
+}
+
+// Check if file is terminal, not pipe or regular file
+func IsTerminal(file *os.File) bool {
+	return isTerminal(file)
+}
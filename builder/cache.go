@@ -0,0 +1,163 @@
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Directory to store generated files of cached tasks, by hash of inputs
+const cacheDir = ".build/cache"
+
+// Generated file stored in cache, content is in file named by index
+type cacheEntry struct {
+	Path string      `json:"path"`
+	Mode os.FileMode `json:"mode"`
+}
+
+// Calculate cache key of task, include sources, expanded commands, dir,
+// environment and matrix, so changed variable used by task miss the cache;
+// referenced tasks are hashed by their commands, and variables are never
+// asked for key
+func cacheKey(run *buildRun, task string, define Task) (string, error) {
+	sources, err := sourcesChecksum(define)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	write := func(str string) error {
+		str, err := run.replaceVariables(str, lookupAnswered)
+		if err != nil {
+			return err
+		}
+		io.WriteString(hash, str+"\x00")
+		return nil
+	}
+	io.WriteString(hash, task+"\x00"+sources+"\x00")
+	visited := map[string]bool{task: true}
+	var writeTask func(define Task) error
+	var writeCommands func(cmds []Command) error
+	writeCommands = func(cmds []Command) error {
+		for _, cmd := range cmds {
+			if err := writeCommands(cmd.Parallel); err != nil {
+				return err
+			}
+			if ref := commandRef(cmd.Cmd); ref != "" {
+				io.WriteString(hash, "task "+ref+"\x00")
+				if visited[ref] {
+					continue
+				}
+				visited[ref] = true
+				if err := writeTask(currentMap().Task[ref]); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, str := range append(append([]string{cmd.Cmd}, cmd.Pipe...), cmd.Output) {
+				if err := write(str); err != nil {
+					return err
+				}
+			}
+			for _, name := range sortedKeys(cmd.Env) {
+				if err := write(name + "=" + cmd.Env[name]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	writeTask = func(define Task) error {
+		if err := writeCommands(define.Cmds); err != nil {
+			return err
+		}
+		if err := write(define.Dir); err != nil {
+			return err
+		}
+		for _, name := range sortedKeys(define.Env) {
+			if err := write(name + "=" + define.Env[name]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := writeTask(define); err != nil {
+		return "", err
+	}
+	matrix, _ := json.Marshal(define.Matrix)
+	hash.Write(matrix)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Lookup variable without ask, use answer if already asked; variable not
+// found is kept as is
+func lookupAnswered(name string) (string, bool) {
+	if value, ok := lookupVariable(name); ok {
+		return value, true
+	}
+	answerLock.Lock()
+	defer answerLock.Unlock()
+	if value, ok := answers[name]; ok {
+		return value, true
+	}
+	return "${" + name + "}", true
+}
+
+// Restore generated files from cache, return false if not cached
+func restoreCache(key string) (bool, error) {
+	dir := filepath.Join(cacheDir, key)
+	content, err := ioutil.ReadFile(filepath.Join(dir, "files.json"))
+	if err != nil {
+		return false, nil
+	}
+	var entries []cacheEntry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return false, nil
+	}
+	for idx, entry := range entries {
+		if err := copyFile(filepath.Join(dir, strconv.Itoa(idx)), filepath.FromSlash(entry.Path), entry.Mode); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Store generated files of task in cache after task run successfully
+func saveCache(key string, define Task) error {
+	files, err := globFiles(define.Generates)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+	// Write to temporary directory, then rename, so broken cache is never
+	// restored
+	tmp, err := ioutil.TempDir(cacheDir, "tmp")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	var entries []cacheEntry
+	for idx, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := copyFile(path, filepath.Join(tmp, strconv.Itoa(idx)), info.Mode().Perm()); err != nil {
+			return err
+		}
+		entries = append(entries, cacheEntry{filepath.ToSlash(path), info.Mode().Perm()})
+	}
+	content, _ := json.Marshal(entries)
+	if err := ioutil.WriteFile(filepath.Join(tmp, "files.json"), content, 0644); err != nil {
+		return err
+	}
+	dir := filepath.Join(cacheDir, key)
+	os.RemoveAll(dir)
+	return os.Rename(tmp, dir)
+}
@@ -0,0 +1,51 @@
+package builder
+
+import (
+	"errors"
+	"os/exec"
+	"time"
+)
+
+// Error of build run canceled by its context, like watch triggered run
+// superseded by newer change
+var errCanceled = errors.New("Run Canceled")
+
+// Error of build run exceed --max-duration, its commands are terminated
+type durationError string
+
+func (err durationError) Error() string {
+	return string(err)
+}
+
+// Policies of watch triggered task while it is running: queue a run after
+// it, drop the trigger, or cancel it and run again
+var watchPolicies = []string{"queue", "drop", "cancel"}
+
+// Check if context of build run is done
+func (run *buildRun) canceled() bool {
+	return run.ctx.Err() != nil
+}
+
+// Terminate commands when context of run is done before they exit, done
+// is closed when they exit
+func cancelProcesses(run *buildRun, cmds []*exec.Cmd, done chan struct{}) {
+	if run.ctx.Done() == nil {
+		return
+	}
+	go func() {
+		select {
+		case <-run.ctx.Done():
+			for _, cmd := range cmds {
+				stopProcess(cmd)
+			}
+			select {
+			case <-done:
+			case <-time.After(killGrace):
+				for _, cmd := range cmds {
+					killProcess(cmd)
+				}
+			}
+		case <-done:
+		}
+	}()
+}
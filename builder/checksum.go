@@ -0,0 +1,222 @@
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Directory to store checksum of task sources
+const checksumDir = ".build/checksum"
+
+// Unsafe characters for file name of checksum
+var checksumNameRegex = regexp.MustCompile("[^A-Za-z0-9_.-]")
+
+// Expand variables in patterns and find all matched files, sorted
+func globFiles(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		pattern, err := parseVariable(pattern)
+		if err != nil {
+			return nil, err
+		}
+		matches, err := globPath(expandPath(pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range matches {
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				files = append(files, path)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Calculate checksum of task sources, include file path and content
+func sourcesChecksum(define Task) (string, error) {
+	files, err := globFiles(define.Sources)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	for _, path := range files {
+		file, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		io.WriteString(hash, filepath.ToSlash(path)+"\x00")
+		_, err = io.Copy(hash, file)
+		file.Close()
+		if err != nil {
+			return "", err
+		}
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Get file path of task checksum
+func checksumFile(task string) string {
+	return filepath.Join(checksumDir, checksumNameRegex.ReplaceAllString(task, "_"))
+}
+
+// Check if task sources not changed since last run and generated files
+// exist, return current checksum to save after task run
+func isUpToDate(task string, define Task) (bool, string, error) {
+	checksum, err := sourcesChecksum(define)
+	if err != nil {
+		return false, "", err
+	}
+	saved, err := ioutil.ReadFile(checksumFile(task))
+	if err != nil || strings.TrimSpace(string(saved)) != checksum {
+		return false, checksum, nil
+	}
+	for _, pattern := range define.Generates {
+		files, err := globFiles([]string{pattern})
+		if err != nil {
+			return false, "", err
+		}
+		if len(files) == 0 {
+			return false, checksum, nil
+		}
+	}
+	// Generated files changed since last run, like edited or partially
+	// overwritten
+	if define.Fingerprint {
+		saved, err := ioutil.ReadFile(fingerprintFile(task))
+		if err != nil {
+			return false, checksum, nil
+		}
+		current, err := generatesFingerprint(define)
+		if err != nil || current != string(saved) {
+			return false, checksum, nil
+		}
+	}
+	return true, checksum, nil
+}
+
+// Save checksum of task sources after task run successfully
+func saveChecksum(task string, checksum string) error {
+	if err := os.MkdirAll(checksumDir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(checksumFile(task), []byte(checksum+"\n"), 0644)
+}
+
+// Content hash of changed file at last triggered run of task, by task and
+// path
+var triggerHashes = make(map[string]string)
+var triggerHashLock sync.Mutex
+
+// Check if content of changed file is same as at last triggered run of
+// task, and remember current content; file could not be read is changed
+func unchangedContent(task string, path string) bool {
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return false
+	}
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return false
+	}
+	key := task + "\x00" + filepath.Clean(path)
+	triggerHashLock.Lock()
+	defer triggerHashLock.Unlock()
+	last, ok := triggerHashes[key]
+	triggerHashes[key] = sum
+	return ok && last == sum
+}
+
+// Calculate checksum of file content
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Get file path of generated files fingerprint of task
+func fingerprintFile(task string) string {
+	return checksumFile(task) + ".generates"
+}
+
+// Check every generates pattern of task match file after run, return
+// patterns match nothing
+func missingGenerates(define Task) ([]string, error) {
+	var missing []string
+	for _, pattern := range define.Generates {
+		files, err := globFiles([]string{pattern})
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			if parsed, err := parseVariable(pattern); err == nil {
+				pattern = parsed
+			}
+			missing = append(missing, pattern)
+		}
+	}
+	return missing, nil
+}
+
+// Checksum and path of each generated file of task, a line per file like
+// sha256sum output
+func generatesFingerprint(define Task) (string, error) {
+	files, err := globFiles(define.Generates)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, path := range files {
+		sum, err := fileChecksum(path)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, sum+"  "+filepath.ToSlash(path)+"\n")
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// Verify generated files of task exist after run, and save their checksums
+// if fingerprint enabled; missing files terminate the task
+func verifyGenerates(run *buildRun, task string, define Task) error {
+	if len(define.Generates) == 0 {
+		return nil
+	}
+	missing, err := missingGenerates(define)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		err := &taskError{task, errors.New("Generated Files Missing: " + strings.Join(missing, ", "))}
+		logTask(task, -1, "", CLR_R, err.Error())
+		run.fail(err)
+		return err
+	}
+	if !define.Fingerprint {
+		return nil
+	}
+	fingerprint, err := generatesFingerprint(define)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(checksumDir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fingerprintFile(task), []byte(fingerprint), 0644)
+}
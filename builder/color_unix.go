@@ -0,0 +1,13 @@
+//go:build !windows
+// +build !windows
+
+package builder
+
+import (
+	"os"
+)
+
+// Terminal on unix support ANSI escape code already
+func enableColor(file *os.File) bool {
+	return true
+}
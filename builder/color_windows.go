@@ -0,0 +1,31 @@
+package builder
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Console mode flag to handle ANSI escape code, since windows 10
+const enableVirtualTerminalProcessing = 0x0004
+
+var (
+	kernel32           = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleMode = kernel32.NewProc("GetConsoleMode")
+	procSetConsoleMode = kernel32.NewProc("SetConsoleMode")
+)
+
+// Enable virtual terminal processing of console, older console could not
+// show color
+func enableColor(file *os.File) bool {
+	var mode uint32
+	handle := file.Fd()
+	if ret, _, _ := procGetConsoleMode.Call(handle, uintptr(unsafe.Pointer(&mode))); ret == 0 {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	ret, _, _ := procSetConsoleMode.Call(handle, uintptr(mode|enableVirtualTerminalProcessing))
+	return ret != 0
+}
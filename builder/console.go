@@ -0,0 +1,275 @@
+package builder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Tasks of last build run, rerun by console
+var lastTasks []string
+var lastTasksLock sync.Mutex
+
+// Remember tasks of build run for rerun
+func setLastTasks(tasks []string) {
+	lastTasksLock.Lock()
+	lastTasks = tasks
+	lastTasksLock.Unlock()
+}
+
+// Console is reading stdin, and pipe forward console input to running
+// interactive command; restore is set if terminal is in raw mode
+var consoleStarted bool
+var consoleInput *os.File
+var consoleRestore func()
+var consoleLock sync.Mutex
+
+// Read console keys while watching: r rerun last tasks, l list tasks, s
+// print summary of last build run, q quit, or : and task names to run them,
+// so tasks named like keys still could run; terminal is in raw mode to read
+// key without Enter, or read lines if not supported. Input is forwarded to
+// interactive command if running
+func startConsole() {
+	restore, err := makeRaw(os.Stdin)
+	consoleLock.Lock()
+	consoleStarted = true
+	consoleRestore = restore
+	consoleLock.Unlock()
+	if err != nil {
+		log(CLR_G, "Type r to rerun, l to list tasks, q to quit, or :task names to run, then Enter")
+		go readConsoleLines()
+		return
+	}
+	log(CLR_G, "Press r to rerun, l to list tasks, q to quit, or : then task names and Enter to run")
+	go readConsoleKeys()
+}
+
+// Restore terminal mode of console, before exit
+func stopConsole() {
+	consoleLock.Lock()
+	restore := consoleRestore
+	consoleRestore = nil
+	consoleLock.Unlock()
+	if restore != nil {
+		restore()
+	}
+}
+
+// Get pipe of running interactive command, nil if not running
+func consoleForward() *os.File {
+	consoleLock.Lock()
+	defer consoleLock.Unlock()
+	return consoleInput
+}
+
+// Read console input line by line, terminal not support raw mode
+func readConsoleLines() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if input := consoleForward(); input != nil {
+			input.WriteString(scanner.Text() + "\n")
+			continue
+		}
+		handleConsole(strings.TrimSpace(scanner.Text()))
+	}
+}
+
+// Read console input key by key in raw mode, keys after : are echoed and
+// edited until Enter, Esc cancel it
+func readConsoleKeys() {
+	buf := make([]byte, 64)
+	var line []byte
+	typing := false
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+		if input := consoleForward(); input != nil {
+			input.Write(buf[:n])
+			continue
+		}
+		for _, key := range buf[:n] {
+			switch {
+			case typing && (key == '\r' || key == '\n'):
+				fmt.Println()
+				typing = false
+				handleConsole(":" + string(line))
+				line = nil
+			case typing && (key == 0x7f || key == '\b'):
+				if len(line) > 0 {
+					line = line[:len(line)-1]
+					fmt.Print("\b \b")
+				}
+			case typing && key == 0x1b:
+				fmt.Println()
+				typing = false
+				line = nil
+			case typing:
+				line = append(line, key)
+				fmt.Print(string(key))
+			case key == ':':
+				typing = true
+				fmt.Print(":")
+			case strings.IndexByte("rlsq", key) >= 0:
+				handleConsole(string(key))
+			}
+		}
+	}
+}
+
+// Get stdin of interactive command, a pipe fed by console if console is
+// reading stdin, terminal is back to normal mode for echo and line editing
+// meanwhile; release after command exit
+func interactiveStdin() (*os.File, func(), error) {
+	consoleLock.Lock()
+	defer consoleLock.Unlock()
+	if !consoleStarted {
+		return os.Stdin, nil, nil
+	}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	consoleInput = writer
+	raw := consoleRestore != nil
+	if raw {
+		consoleRestore()
+	}
+	release := func() {
+		consoleLock.Lock()
+		if consoleInput == writer {
+			consoleInput = nil
+			if raw && consoleRestore != nil {
+				if restore, err := makeRaw(os.Stdin); err == nil {
+					consoleRestore = restore
+				}
+			}
+		}
+		consoleLock.Unlock()
+		writer.Close()
+	}
+	return reader, release, nil
+}
+
+// Handle a line of console input, task names after : run even if named like
+// key
+func handleConsole(input string) {
+	if strings.HasPrefix(input, ":") {
+		runConsoleTasks(input[1:])
+		return
+	}
+	switch input {
+	case "":
+	case "l":
+		listTasks()
+	case "q":
+		log(CLR_G, "Shutting Down By Console")
+		shutdown(0)
+	case "r":
+		rerunTasks(nil)
+	case "s":
+		printLastSummary()
+	default:
+		runConsoleTasks(input)
+	}
+}
+
+// Run task names of console input, check all of them exist first
+func runConsoleTasks(input string) {
+	tasks := strings.Fields(input)
+	if len(tasks) == 0 {
+		return
+	}
+	for idx, task := range tasks {
+		task = resolveAlias(task)
+		tasks[idx] = task
+		if _, ok := currentMap().Task[task]; !ok {
+			log(CLR_R, "Task \""+task+"\" Not Found")
+			return
+		}
+	}
+	rerunTasks(tasks)
+}
+
+// Run tasks manually, or tasks of last build run if not specified
+func rerunTasks(tasks []string) {
+	if len(tasks) == 0 {
+		lastTasksLock.Lock()
+		tasks = lastTasks
+		lastTasksLock.Unlock()
+	}
+	if len(tasks) == 0 {
+		return
+	}
+	clearBeforeRun()
+	go func() {
+		run := newBuildRun("")
+		run.trigger = "console"
+		handleError(runTaskLimited(run, tasks...))
+	}()
+}
+
+// Only one question asked at a time
+var promptLock sync.Mutex
+
+// Ask question and read a line of answer from console or stdin, return
+// io.EOF if stdin closed
+func prompt(question string) (string, error) {
+	if tuiActive() {
+		return "", errors.New("Prompt Not Supported in TUI")
+	}
+	promptLock.Lock()
+	defer promptLock.Unlock()
+	stdin, release, err := interactiveStdin()
+	if err != nil {
+		return "", err
+	}
+	if release != nil {
+		defer release()
+		defer stdin.Close()
+	}
+	if porcelain {
+		emitEvent("prompt", map[string]interface{}{"message": question})
+	} else if noColor {
+		fmt.Print(question + " ")
+	} else {
+		fmt.Print(CLR_Y + question + "\x1b[0m ")
+	}
+	// Read byte by byte, not consume input after the line
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		if _, err := stdin.Read(buf); err != nil {
+			if err == io.EOF && len(line) > 0 {
+				break
+			}
+			if !porcelain {
+				fmt.Println()
+			}
+			return "", err
+		}
+		if buf[0] == '\n' {
+			break
+		}
+		line = append(line, buf[0])
+	}
+	return strings.TrimSpace(string(line)), nil
+}
+
+// Ask to confirm task run by y/N, return false if declined or stdin closed
+func confirm(run *buildRun, message string) bool {
+	if run.opts.Yes {
+		return true
+	}
+	answer, err := prompt(message + " [y/N]")
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
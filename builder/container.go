@@ -0,0 +1,88 @@
+package builder
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+)
+
+// Container to run commands of task, write as image name, or object
+type Container struct {
+	// Image to run commands by docker run, container removed after command
+	Image string
+	// Name of running container to run commands by docker exec instead
+	Name string
+	// Working directory in container, project directory is mounted at it
+	// for docker run; default is /work
+	Workdir string
+	// Extra options of docker run or docker exec, like --network=host
+	Options []string
+}
+
+// Support image name as container define
+func (container *Container) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&container.Image); err == nil {
+		return nil
+	}
+	type containerDefine Container
+	return unmarshal((*containerDefine)(container))
+}
+
+// Support image name as container define
+func (container *Container) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &container.Image); err == nil {
+		return nil
+	}
+	type containerDefine Container
+	return json.Unmarshal(data, (*containerDefine)(container))
+}
+
+// Support image name as container define
+func (container *Container) UnmarshalTOML(value interface{}) error {
+	return unmarshalTOML(value, container)
+}
+
+// Build docker command which run command in container by shell, dir of
+// task is relative to working directory in container, env is passed by -e
+func containerCommand(run *buildRun, container *Container, command string, dir string, env []string) (*exec.Cmd, error) {
+	var err error
+	define := *container
+	for _, value := range []*string{&define.Image, &define.Name, &define.Workdir} {
+		if *value, err = run.parseVariable(*value); err != nil {
+			return nil, err
+		}
+	}
+	if define.Image == "" && define.Name == "" {
+		return nil, configError("Container Image or Name Required")
+	}
+	if define.Workdir == "" {
+		define.Workdir = "/work"
+	}
+	workdir := define.Workdir
+	if dir != "" && !filepath.IsAbs(dir) {
+		workdir = path.Join(workdir, filepath.ToSlash(dir))
+	}
+	var args []string
+	if define.Name != "" {
+		args = append(args, "exec", "-i", "-w", workdir)
+	} else {
+		project, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "run", "--rm", "-i", "-v", project+":"+define.Workdir, "-w", workdir)
+	}
+	for _, pair := range env {
+		args = append(args, "-e", pair)
+	}
+	args = append(args, define.Options...)
+	if define.Name != "" {
+		args = append(args, define.Name)
+	} else {
+		args = append(args, define.Image)
+	}
+	args = append(args, "sh", "-c", command)
+	return exec.Command("docker", args...), nil
+}
@@ -0,0 +1,96 @@
+package builder
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Load KEY=VALUE entries from env file, merge into variables of build map
+// being loaded, their names are kept to pass to command environment
+func loadEnvFile(path string, define *BuildMap) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		// Skip empty line and comment
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		idx := strings.Index(line, "=")
+		if idx <= 0 {
+			return fmt.Errorf("Env File %s:%d Invalid Line", path, lineNo)
+		}
+		key := strings.TrimSpace(line[:idx])
+		value := strings.TrimSpace(line[idx+1:])
+		// Strip quote around value
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		if !containsString(define.envKeys, key) {
+			define.envKeys = append(define.envKeys, key)
+		}
+		define.Variable[key] = value
+	}
+	return scanner.Err()
+}
+
+// Environment for command, with variables loaded from env file
+func commandEnv() []string {
+	return currentMap().commandEnv()
+}
+
+// Environment for command, with variables loaded from env file of build map
+func (config BuildMap) commandEnv() []string {
+	env := os.Environ()
+	for _, key := range config.envKeys {
+		env = append(env, key+"="+config.Variable[key])
+	}
+	return env
+}
+
+// Environment for commands of task; clean env task only inherit allowed
+// variables of process, container and remote task always inherit all for
+// docker and ssh client
+func taskEnv(task string) []string {
+	config := currentMap()
+	define := config.Task[task]
+	if !define.CleanEnv || define.Container != nil || define.Remote != nil {
+		return config.commandEnv()
+	}
+	env := []string{}
+	for _, pair := range os.Environ() {
+		name := pair
+		if idx := strings.Index(pair, "="); idx > 0 {
+			name = pair[:idx]
+		}
+		for _, pattern := range define.EnvAllow {
+			if ok, _ := path.Match(pattern, name); ok {
+				env = append(env, pair)
+				break
+			}
+		}
+	}
+	for _, key := range config.envKeys {
+		env = append(env, key+"="+config.Variable[key])
+	}
+	return env
+}
+
+// Get value of name in environment list, later one take precedence
+func lookupEnv(env []string, name string) string {
+	value := ""
+	for _, pair := range env {
+		if strings.HasPrefix(pair, name+"=") {
+			value = pair[len(name)+1:]
+		}
+	}
+	return value
+}
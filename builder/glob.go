@@ -0,0 +1,187 @@
+package builder
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Check if pattern has ** to match any level of directories
+func isRecursive(pattern string) bool {
+	return strings.Contains(pattern, "**")
+}
+
+// Match file path with pattern, ** match any level of directories
+func matchPath(pattern string, name string) (bool, error) {
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	name = filepath.ToSlash(filepath.Clean(name))
+	if !isRecursive(pattern) {
+		return path.Match(pattern, name)
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// Match changed file with watch pattern, file of event could be absolute
+// while pattern is relative to working directory or the opposite, so file
+// is also tried in same form of pattern
+func matchWatch(pattern string, name string) (bool, error) {
+	ok, err := matchPath(pattern, name)
+	if err != nil || ok {
+		return ok, err
+	}
+	patternAbs := filepath.IsAbs(filepath.FromSlash(pattern))
+	if patternAbs == filepath.IsAbs(name) {
+		return false, nil
+	}
+	if patternAbs {
+		if name, err = filepath.Abs(name); err != nil {
+			return false, nil
+		}
+	} else if name = relativePath(name); name == "" {
+		return false, nil
+	}
+	return matchPath(pattern, name)
+}
+
+// Get absolute path as relative to working directory, empty if not under
+// it
+func relativePath(name string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(cwd, name)
+	if err != nil || rel == ".." || strings.HasPrefix(filepath.ToSlash(rel), "../") {
+		return ""
+	}
+	return rel
+}
+
+// Match path segments one by one, ** could consume zero or more segments
+func matchSegments(patterns []string, names []string) (bool, error) {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for idx := 0; idx <= len(names); idx++ {
+				if ok, err := matchSegments(patterns[1:], names[idx:]); err != nil || ok {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(names) == 0 {
+			return false, nil
+		}
+		if ok, err := path.Match(patterns[0], names[0]); err != nil || !ok {
+			return false, err
+		}
+		patterns, names = patterns[1:], names[1:]
+	}
+	return len(names) == 0, nil
+}
+
+// Check if path is ignored by any pattern, pattern without slash match any
+// path segment like .git, otherwise match the path or its parent directory;
+// path ignored by .buildignore is also ignored
+func matchIgnore(patterns []string, name string) bool {
+	if ignoredByFile(name) {
+		return true
+	}
+	// Ignore pattern with slash is relative to working directory
+	if filepath.IsAbs(name) {
+		if rel := relativePath(name); rel != "" {
+			name = rel
+		}
+	}
+	segments := strings.Split(filepath.ToSlash(filepath.Clean(name)), "/")
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(filepath.Clean(pattern))
+		for idx, segment := range segments {
+			var ok bool
+			if strings.Contains(pattern, "/") {
+				ok, _ = matchPath(pattern, strings.Join(segments[:idx+1], "/"))
+			} else {
+				ok, _ = path.Match(pattern, segment)
+			}
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Get directory part of pattern before any wildcard
+func patternBase(pattern string) string {
+	segments := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	var base []string
+	for _, segment := range segments {
+		if strings.ContainsAny(segment, "*?[") {
+			break
+		}
+		base = append(base, segment)
+	}
+	if len(base) == len(segments) {
+		return filepath.Dir(pattern)
+	}
+	if len(base) == 0 {
+		return "."
+	}
+	if len(base) == 1 && base[0] == "" {
+		return string(filepath.Separator)
+	}
+	return filepath.FromSlash(strings.Join(base, "/"))
+}
+
+// Find files match pattern, support ** to match recursively
+func globPath(pattern string) ([]string, error) {
+	if !isRecursive(pattern) {
+		return filepath.Glob(pattern)
+	}
+	var matches []string
+	err := filepath.Walk(patternBase(pattern), func(path string, info os.FileInfo, err error) error {
+		// Skip unreadable path
+		if err != nil {
+			return nil
+		}
+		ok, err := matchPath(pattern, path)
+		if err != nil {
+			return err
+		}
+		if ok {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// Get directories need to watch for pattern, directories of matched files,
+// or all directories under pattern base if pattern is recursive, ignored
+// path will be skipped
+func watchPaths(pattern string, ignore []string) ([]string, error) {
+	var dirs []string
+	if !isRecursive(pattern) {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range matches {
+			if !matchIgnore(ignore, path) {
+				dirs = append(dirs, filepath.Dir(path))
+			}
+		}
+		return dirs, nil
+	}
+	err := filepath.Walk(patternBase(pattern), func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if matchIgnore(ignore, path) {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	return dirs, err
+}
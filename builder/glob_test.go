@@ -0,0 +1,67 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "builder/build.go", false},
+		{"builder/*.go", "builder/build.go", true},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "builder/build.go", true},
+		{"**/*.go", "a/b/c/d.go", true},
+		{"src/**", "src/a/b.less", true},
+		{"src/**/*.less", "src/app.less", true},
+		{"src/**/*.less", "lib/app.less", false},
+		{"./src/*.less", "src/app.less", true},
+		{"src/*.less", "./src/app.less", true},
+	}
+	for _, test := range tests {
+		got, err := matchPath(test.pattern, test.name)
+		if err != nil {
+			t.Errorf("matchPath(%q, %q) error: %s", test.pattern, test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("matchPath(%q, %q) = %v, want %v", test.pattern, test.name, got, test.want)
+		}
+	}
+}
+
+func TestMatchWatch(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "glob.go", true},
+		{"*.go", filepath.Join(cwd, "glob.go"), true},
+		{filepath.ToSlash(cwd) + "/*.go", "glob.go", true},
+		{filepath.ToSlash(cwd) + "/*.go", filepath.Join(cwd, "glob.go"), true},
+		{"*.go", filepath.Join(filepath.Dir(cwd), "main.go"), false},
+		{"**/*.go", filepath.Join(cwd, "a", "b.go"), true},
+		{"*.less", "glob.go", false},
+	}
+	for _, test := range tests {
+		got, err := matchWatch(test.pattern, test.name)
+		if err != nil {
+			t.Errorf("matchWatch(%q, %q) error: %s", test.pattern, test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("matchWatch(%q, %q) = %v, want %v", test.pattern, test.name, got, test.want)
+		}
+	}
+}
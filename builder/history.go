@@ -0,0 +1,145 @@
+package builder
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// History of build runs, one JSON record per line, appended when --record
+const historyFile = ".build/history.jsonl"
+
+var historyLock sync.Mutex
+
+// Build run in history, trigger is command, watch, schedule, console or
+// server; status is done, failed or canceled
+type HistoryRun struct {
+	Time      time.Time     `json:"time"`
+	Trigger   string        `json:"trigger"`
+	File      string        `json:"file,omitempty"`
+	Tasks     []string      `json:"tasks"`
+	Status    string        `json:"status"`
+	ElapsedMs float64       `json:"elapsed_ms"`
+	Results   []HistoryTask `json:"results"`
+}
+
+// Task finished in build run, with its commands
+type HistoryTask struct {
+	Task      string           `json:"task"`
+	Status    string           `json:"status"`
+	ElapsedMs float64          `json:"elapsed_ms"`
+	Commands  []HistoryCommand `json:"commands,omitempty"`
+}
+
+// Command run by task, name is task and index like build [0]
+type HistoryCommand struct {
+	Name      string  `json:"name"`
+	Command   string  `json:"command"`
+	ElapsedMs float64 `json:"elapsed_ms"`
+	ExitCode  int     `json:"exit_code"`
+}
+
+// Record finished command of task, task reference is not recorded
+func (run *buildRun) recordCommand(task string, index int, command Command, elapsed time.Duration, err error) {
+	if !run.opts.Record {
+		return
+	}
+	line := command.Cmd
+	if len(command.Pipe) > 0 {
+		line = strings.Join(command.Pipe, " | ")
+	} else if len(command.Parallel) > 0 {
+		var cmds []string
+		for _, cmd := range command.Parallel {
+			cmds = append(cmds, cmd.Cmd)
+		}
+		line = strings.Join(cmds, " & ")
+	}
+	run.resultLock.Lock()
+	run.steps[task] = append(run.steps[task], HistoryCommand{
+		Name:      task + " [" + strconv.Itoa(index) + "]",
+		Command:   line,
+		ElapsedMs: elapsed.Seconds() * 1000,
+		ExitCode:  exitCode(err),
+	})
+	run.resultLock.Unlock()
+}
+
+// Get exit code of command error, 0 if succeed, 1 if not exited by code
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
+
+// Append finished build run to history file
+func (run *buildRun) saveHistory(tasks []string, start time.Time, err error) {
+	if !run.opts.Record {
+		return
+	}
+	status := "done"
+	if err == errCanceled {
+		status = "canceled"
+	} else if err != nil {
+		status = "failed"
+	}
+	record := HistoryRun{
+		Time:      start,
+		Trigger:   run.trigger,
+		File:      run.vars["FILE"],
+		Tasks:     tasks,
+		Status:    status,
+		ElapsedMs: time.Since(start).Seconds() * 1000,
+	}
+	run.resultLock.Lock()
+	for _, result := range run.results {
+		record.Results = append(record.Results, HistoryTask{
+			Task:      result.task,
+			Status:    result.status,
+			ElapsedMs: result.elapsed.Seconds() * 1000,
+			Commands:  run.steps[result.task],
+		})
+		delete(run.steps, result.task)
+	}
+	run.resultLock.Unlock()
+	line, _ := json.Marshal(record)
+	historyLock.Lock()
+	defer historyLock.Unlock()
+	os.MkdirAll(filepath.Dir(historyFile), 0755)
+	file, err := os.OpenFile(historyFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log(CLR_R, "History "+err.Error())
+		return
+	}
+	defer file.Close()
+	file.Write(append(line, '\n'))
+}
+
+// Read build runs in history file of current directory, from oldest;
+// broken lines are skipped
+func ReadHistory() ([]HistoryRun, error) {
+	file, err := os.Open(historyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var runs []HistoryRun
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var run HistoryRun
+		if err := json.Unmarshal(scanner.Bytes(), &run); err == nil {
+			runs = append(runs, run)
+		}
+	}
+	return runs, scanner.Err()
+}
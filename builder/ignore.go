@@ -0,0 +1,134 @@
+package builder
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Ignore file in gitignore syntax, paths match it are never watched
+const ignoreFile = ".buildignore"
+
+// Rule of ignore file, later rule override earlier one
+type ignoreRule struct {
+	pattern string
+	// Rule start with ! include path again
+	negate bool
+	// Rule end with / only match directory
+	dirOnly bool
+	// Rule contain / is relative to directory of ignore file, otherwise
+	// match name in any level
+	anchored bool
+}
+
+// Rules of ignore file in current directory, reload with config
+var ignoreRules []ignoreRule
+var ignoreLock sync.RWMutex
+
+// Load rules of ignore file, no rule if file not exists
+func loadIgnoreFile() error {
+	var rules []ignoreRule
+	file, err := os.Open(ignoreFile)
+	if err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if rule, ok := parseIgnoreRule(scanner.Text()); ok {
+				rules = append(rules, rule)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	ignoreLock.Lock()
+	ignoreRules = rules
+	ignoreLock.Unlock()
+	return nil
+}
+
+// Parse a line of ignore file, skip blank line and comment
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	var rule ignoreRule
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+	rule.pattern = line
+	return rule, true
+}
+
+// Check if path is ignored by ignore file, path in ignored directory is
+// ignored too
+func ignoredByFile(name string) bool {
+	ignoreLock.RLock()
+	rules := ignoreRules
+	ignoreLock.RUnlock()
+	if len(rules) == 0 {
+		return false
+	}
+	if filepath.IsAbs(name) {
+		if name = relativePath(name); name == "" {
+			return false
+		}
+	}
+	name = filepath.ToSlash(filepath.Clean(name))
+	if name == "." || strings.HasPrefix(name, "../") {
+		return false
+	}
+	segments := strings.Split(name, "/")
+	for idx := range segments {
+		isDir := idx < len(segments)-1
+		if !isDir {
+			if info, err := os.Stat(name); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		if matchIgnoreRules(rules, segments[:idx+1], isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check path segments with rules, the last matched rule decide
+func matchIgnoreRules(rules []ignoreRule, segments []string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		var ok bool
+		if rule.anchored {
+			ok, _ = matchPath(rule.pattern, strings.Join(segments, "/"))
+		} else {
+			ok, _ = path.Match(rule.pattern, segments[len(segments)-1])
+		}
+		if ok {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,85 @@
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseIgnoreRule(t *testing.T) {
+	tests := []struct {
+		line string
+		want ignoreRule
+		ok   bool
+	}{
+		{"", ignoreRule{}, false},
+		{"   ", ignoreRule{}, false},
+		{"# comment", ignoreRule{}, false},
+		{"node_modules", ignoreRule{pattern: "node_modules"}, true},
+		{"*.log  ", ignoreRule{pattern: "*.log"}, true},
+		{"!keep.log", ignoreRule{pattern: "keep.log", negate: true}, true},
+		{"\\#file", ignoreRule{pattern: "#file"}, true},
+		{"\\!file", ignoreRule{pattern: "!file"}, true},
+		{"dist/", ignoreRule{pattern: "dist", dirOnly: true}, true},
+		{"/build", ignoreRule{pattern: "build", anchored: true}, true},
+		{"src/gen/", ignoreRule{pattern: "src/gen", dirOnly: true, anchored: true}, true},
+		{"/", ignoreRule{}, false},
+	}
+	for _, test := range tests {
+		got, ok := parseIgnoreRule(test.line)
+		if ok != test.ok || (ok && got != test.want) {
+			t.Errorf("parseIgnoreRule(%q) = %+v, %v, want %+v, %v", test.line, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestIgnoredByFile(t *testing.T) {
+	dir := t.TempDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+	os.MkdirAll(filepath.Join("src", "gen"), 0755)
+	os.MkdirAll("dist", 0755)
+	ioutil.WriteFile("dist.txt", nil, 0644)
+	rules := "# generated\n*.log\n!keep.log\ndist/\n/tmp\nsrc/gen\n"
+	if err := ioutil.WriteFile(ignoreFile, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadIgnoreFile(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		ignoreLock.Lock()
+		ignoreRules = nil
+		ignoreLock.Unlock()
+	}()
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"app.log", true},
+		{"logs/app.log", true},
+		{"keep.log", false},
+		{"dist", true},
+		{"dist/app.js", true},
+		{"dist.txt", false},
+		{"tmp/a.txt", true},
+		{"src/tmp/a.txt", false},
+		{"src/gen/a.go", true},
+		{"src/main.go", false},
+		{filepath.Join(dir, "app.log"), true},
+		{filepath.Join(dir, "src", "main.go"), false},
+		{".", false},
+	}
+	for _, test := range tests {
+		if got := ignoredByFile(test.name); got != test.want {
+			t.Errorf("ignoredByFile(%q) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package builder
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Interval to check if running instance exited, for wait policy
+const instanceWaitInterval = 500 * time.Millisecond
+
+// Lock file of instance, locked by checked instance while it run, so
+// instances started at same time could not both pass the check; never
+// removed, lock is released by system when process exit
+const instanceLockFile = ".build/instance.lock"
+
+// Lock file held by this process, kept open until exit
+var instanceLock *os.File
+var instanceLockMutex sync.Mutex
+
+// Lock instance of current directory, false if locked by other instance
+func lockInstance() (bool, error) {
+	instanceLockMutex.Lock()
+	defer instanceLockMutex.Unlock()
+	if instanceLock != nil {
+		return true, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(instanceLockFile), 0755); err != nil {
+		return false, err
+	}
+	file, err := os.OpenFile(instanceLockFile, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return false, err
+	}
+	locked, err := lockFile(file)
+	if !locked {
+		file.Close()
+		return false, err
+	}
+	instanceLock = file
+	return true, nil
+}
+
+// Get running build.go instance of current directory by session file, stale
+// session of exited process is ignored
+func runningInstance() (session, bool) {
+	current, err := readSession()
+	if err != nil || current.Pid == os.Getpid() {
+		return current, false
+	}
+	if err := requestSession(http.MethodGet, "/daemons", nil); err != nil {
+		return current, false
+	}
+	return current, true
+}
+
+// Check running instance of current directory by policy: error exit, wait
+// it exit, forward tasks to run by it, or allow run both; return true if
+// tasks are forwarded
+func checkInstance(policy string, tasks []string) (bool, error) {
+	if policy != "error" && policy != "wait" && policy != "forward" && policy != "allow" {
+		return false, errors.New("Instance Policy \"" + policy + "\" Not Supported")
+	}
+	if policy == "allow" {
+		return false, nil
+	}
+	locked, err := lockInstance()
+	if err != nil || locked {
+		return false, err
+	}
+	// Session of locked instance may be not written yet, wait for it to
+	// forward tasks
+	current, ok := runningInstance()
+	for policy == "forward" && !ok {
+		time.Sleep(instanceWaitInterval)
+		if locked, err = lockInstance(); err != nil || locked {
+			return false, err
+		}
+		current, ok = runningInstance()
+	}
+	pid := ""
+	if ok {
+		pid = ", Pid " + strconv.Itoa(current.Pid)
+	}
+	switch policy {
+	case "error":
+		return false, errors.New("Instance Already Running In Current Directory" + pid)
+	case "wait":
+		log(CLR_G, "Waiting For Running Instance"+pid)
+		for !locked {
+			time.Sleep(instanceWaitInterval)
+			if locked, err = lockInstance(); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	case "forward":
+		for _, task := range tasks {
+			log(CLR_G, "Forward "+task+" To Running Instance"+pid)
+			if err := requestSessionTimeout(http.MethodPost, "/task/"+url.PathEscape(task)+"?wait=true", nil, 0); err != nil {
+				return true, errors.New("Task \"" + task + "\" Failed In Running Instance: " + err.Error())
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
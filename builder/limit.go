@@ -0,0 +1,85 @@
+package builder
+
+import (
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Max bytes of output line, longer line is truncated; 0 mean no limit
+var maxLineLength int
+
+// Max output lines per second of each command, more lines are suppressed
+// and counted; 0 mean no limit
+var maxLineRate int
+
+// Output lines of command in current second, and lines suppressed
+type lineRate struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// Rate of commands by task and command index
+var lineRates = make(map[string]*lineRate)
+var lineRateLock sync.Mutex
+
+// Truncate line longer than max line length, cut at character boundary
+func truncateLine(line string) string {
+	if maxLineLength <= 0 || len(line) <= maxLineLength {
+		return line
+	}
+	end := maxLineLength
+	for end > 0 && !utf8.RuneStart(line[end]) {
+		end--
+	}
+	return line[:end] + " ... " + strconv.Itoa(len(line)-end) + " Bytes Truncated"
+}
+
+// Check if output line of command could be logged by max line rate, count
+// suppressed line and report them when the second end
+func allowLine(task string, index int) bool {
+	if maxLineRate <= 0 {
+		return true
+	}
+	key := task + " [" + strconv.Itoa(index) + "]"
+	lineRateLock.Lock()
+	defer lineRateLock.Unlock()
+	rate, ok := lineRates[key]
+	now := time.Now()
+	if !ok || now.Sub(rate.start) >= time.Second {
+		if ok && rate.suppressed > 0 {
+			reportSuppressed(task, index, rate.suppressed)
+			rate.suppressed = 0
+		}
+		rate = &lineRate{start: now}
+		lineRates[key] = rate
+	}
+	if rate.count < maxLineRate {
+		rate.count++
+		return true
+	}
+	rate.suppressed++
+	if rate.suppressed == 1 {
+		// Report even if command output nothing after this second
+		time.AfterFunc(time.Second-now.Sub(rate.start), func() {
+			lineRateLock.Lock()
+			defer lineRateLock.Unlock()
+			if rate.suppressed > 0 {
+				reportSuppressed(task, index, rate.suppressed)
+				rate.suppressed = 0
+			}
+		})
+	}
+	return false
+}
+
+// Log count of suppressed lines of command
+func reportSuppressed(task string, index int, count int) {
+	unit := " Lines"
+	if count == 1 {
+		unit = " Line"
+	}
+	logTask(task, index, outputPrefix(task), CLR_G, strconv.Itoa(count)+unit+" Suppressed, Exceed "+strconv.Itoa(maxLineRate)+" Lines Per Second")
+}
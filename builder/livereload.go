@@ -0,0 +1,193 @@
+package builder
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Default port of LiveReload protocol, used by browser extensions
+const liveReloadAddr = ":35729"
+
+// GUID for websocket handshake
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Websocket frame opcode
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// Connected LiveReload clients
+var liveReloadClients = make(map[*liveReloadClient]bool)
+var liveReloadLock sync.Mutex
+
+// Websocket connection of LiveReload client, write is locked
+type liveReloadClient struct {
+	conn net.Conn
+	lock sync.Mutex
+}
+
+// Start LiveReload websocket server
+func startLiveReload() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/livereload", serveLiveReload)
+	log(CLR_G, "LiveReload on "+liveReloadAddr)
+	go func() {
+		if err := http.ListenAndServe(liveReloadAddr, mux); err != nil {
+			log(CLR_R, "LiveReload "+err.Error())
+		}
+	}()
+}
+
+// Push reload command to all clients
+func liveReload(path string) {
+	message, _ := json.Marshal(map[string]interface{}{
+		"command": "reload",
+		"path":    filepath.ToSlash(filepath.Clean(path)),
+		"liveCSS": true,
+	})
+	liveReloadLock.Lock()
+	defer liveReloadLock.Unlock()
+	for client := range liveReloadClients {
+		if err := client.write(opText, message); err != nil {
+			client.conn.Close()
+			delete(liveReloadClients, client)
+		}
+	}
+}
+
+// Upgrade to websocket and handle LiveReload protocol
+func serveLiveReload(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "Websocket Required", http.StatusBadRequest)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Websocket Not Supported", http.StatusInternalServerError)
+		return
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := buf.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+	client := &liveReloadClient{conn: conn}
+	defer func() {
+		liveReloadLock.Lock()
+		delete(liveReloadClients, client)
+		liveReloadLock.Unlock()
+		conn.Close()
+	}()
+	for {
+		opcode, payload, err := readFrame(buf.Reader)
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case opClose:
+			client.write(opClose, nil)
+			return
+		case opPing:
+			client.write(opPong, payload)
+		case opText:
+			var message struct {
+				Command string `json:"command"`
+			}
+			if json.Unmarshal(payload, &message) != nil || message.Command != "hello" {
+				continue
+			}
+			hello, _ := json.Marshal(map[string]interface{}{
+				"command":    "hello",
+				"protocols":  []string{"http://livereload.com/protocols/official-7"},
+				"serverName": "build.go",
+			})
+			if client.write(opText, hello) != nil {
+				return
+			}
+			liveReloadLock.Lock()
+			liveReloadClients[client] = true
+			liveReloadLock.Unlock()
+		}
+	}
+}
+
+// Read a websocket frame from client, client frame is always masked
+func readFrame(reader *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0F
+	if header[1]&0x80 == 0 {
+		return 0, nil, errors.New("Websocket Frame Not Masked")
+	}
+	length := uint64(header[1] & 0x7F)
+	if length == 126 {
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(reader, ext); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	} else if length == 127 {
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(reader, ext); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+	if length > 1<<20 {
+		return 0, nil, errors.New("Websocket Frame Too Large")
+	}
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(reader, mask); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return 0, nil, err
+	}
+	for idx := range payload {
+		payload[idx] ^= mask[idx%4]
+	}
+	return opcode, payload, nil
+}
+
+// Write a websocket frame to client, server frame is not masked
+func (client *liveReloadClient) write(opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	length := len(payload)
+	if length < 126 {
+		frame = append(frame, byte(length))
+	} else if length <= 0xFFFF {
+		frame = append(frame, 126, byte(length>>8), byte(length))
+	} else {
+		ext := make([]byte, 8)
+		binary.BigEndian.PutUint64(ext, uint64(length))
+		frame = append(append(frame, 127), ext...)
+	}
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	_, err := client.conn.Write(append(frame, payload...))
+	return err
+}
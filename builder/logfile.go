@@ -0,0 +1,65 @@
+package builder
+
+import (
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Count of rotated log files to keep, like build.log.1 to build.log.3
+const logBackups = 3
+
+// Log file keep all output, rotate when size exceed max size
+type logFile struct {
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+	lock    sync.Mutex
+}
+
+// Log file for output, nil mean not log to file
+var logOutput *logFile
+
+// Open log file to append, zero max size mean never rotate
+func openLogFile(path string, maxSize int64) (*logFile, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &logFile{path: path, maxSize: maxSize, file: file, size: info.Size()}, nil
+}
+
+// Write line to log file, rotate before write if exceed max size
+func (output *logFile) writeLine(line string) {
+	output.lock.Lock()
+	defer output.lock.Unlock()
+	if output.maxSize > 0 && output.size+int64(len(line)+1) > output.maxSize && output.size > 0 {
+		if err := output.rotate(); err != nil {
+			return
+		}
+	}
+	n, _ := output.file.WriteString(line + "\n")
+	output.size += int64(n)
+}
+
+// Rename log file to backups, then open a new log file
+func (output *logFile) rotate() error {
+	output.file.Close()
+	for idx := logBackups - 1; idx > 0; idx-- {
+		os.Rename(output.path+"."+strconv.Itoa(idx), output.path+"."+strconv.Itoa(idx+1))
+	}
+	os.Rename(output.path, output.path+".1")
+	file, err := os.OpenFile(output.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	output.file = file
+	output.size = 0
+	return nil
+}
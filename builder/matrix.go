@@ -0,0 +1,93 @@
+package builder
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Copy of build run with variables of a matrix combination, state of run
+// is shared
+func (run *buildRun) withMatrix(values map[string]string) *buildRun {
+	scoped := *run
+	scoped.matrix = values
+	return &scoped
+}
+
+// Get all combinations of matrix variables, in order of variable name and
+// value; values could use ${variable}
+func matrixCombinations(run *buildRun, matrix map[string][]string) ([]map[string]string, error) {
+	names := make([]string, 0, len(matrix))
+	for name := range matrix {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	combinations := []map[string]string{{}}
+	for _, name := range names {
+		var next []map[string]string
+		for _, value := range matrix[name] {
+			value, err := run.parseVariable(value)
+			if err != nil {
+				return nil, err
+			}
+			for _, combination := range combinations {
+				extended := make(map[string]string, len(combination)+1)
+				for key, item := range combination {
+					extended[key] = item
+				}
+				extended[name] = value
+				next = append(next, extended)
+			}
+		}
+		combinations = next
+	}
+	return combinations, nil
+}
+
+// Describe matrix combination for log, like GOOS=linux GOARCH=amd64
+func matrixLabel(combination map[string]string) string {
+	pairs := make([]string, 0, len(combination))
+	for _, name := range sortedKeys(combination) {
+		pairs = append(pairs, name+"="+combination[name])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// Run commands of task once per matrix combination, or once if task has no
+// matrix; combinations run concurrently if matrix_parallel, first failure
+// is returned
+func runMatrix(run *buildRun, task string, define Task, daemon bool) error {
+	if len(define.Matrix) == 0 {
+		return runCmds(run, task, define, daemon)
+	}
+	combinations, err := matrixCombinations(run, define.Matrix)
+	if err != nil {
+		return err
+	}
+	if !define.MatrixParallel || run.opts.DryRun {
+		for _, combination := range combinations {
+			logTask(task, -1, "", CLR_G, task+" MATRIX "+matrixLabel(combination))
+			if err := runCmds(run.withMatrix(combination), task, define, daemon); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	errs := make([]error, len(combinations))
+	var group sync.WaitGroup
+	for idx, combination := range combinations {
+		logTask(task, -1, "", CLR_G, task+" MATRIX "+matrixLabel(combination))
+		group.Add(1)
+		go func(idx int, combination map[string]string) {
+			defer group.Done()
+			errs[idx] = runCmds(run.withMatrix(combination), task, define, daemon)
+		}(idx, combination)
+	}
+	group.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Valid namespace name, env is reserved for ${env:NAME}
+var namespaceRegex = regexp.MustCompile("^[A-Za-z0-9_][A-Za-z0-9_-]*$")
+
+// Task or variable reference in string, like ${name}, ${#name} and
+// ${name:-default}
+var nameRefRegex = regexp.MustCompile("\\${(#?)([A-Za-z0-9_-]+(?::[A-Za-z0-9_][A-Za-z0-9_-]*)*)(:-[^}]*)?}")
+
+// Load config of namespace, path is config file or directory contain
+// build.yml, relative to the including config
+func loadNamespace(configFile string, name string, path string, loading []string) (BuildMap, error) {
+	if !namespaceRegex.MatchString(name) || name == "env" {
+		return BuildMap{}, fmt.Errorf("Namespace \"%s\" Invalid", name)
+	}
+	path = expandPath(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(configFile), path)
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		file := filepath.Join(path, "build.yml")
+		for _, other := range []string{"build.yaml", "build.json", "build.toml"} {
+			if _, err := os.Stat(file); err == nil {
+				break
+			}
+			file = filepath.Join(path, other)
+		}
+		path = file
+	}
+	define, err := loadConfig(path, loading)
+	if err != nil {
+		return define, err
+	}
+	return namespaceConfig(name, filepath.Dir(filepath.Clean(path)), define), nil
+}
+
+// Prefix tasks and variables of config with namespace, like api:build, and
+// resolve relative paths against directory of the config
+func namespaceConfig(name string, dir string, define BuildMap) BuildMap {
+	names := make(map[string]bool)
+	for task := range define.Task {
+		names[task] = true
+	}
+	for variable := range define.Variable {
+		names[variable] = true
+	}
+	for variable := range define.Prompt {
+		names[variable] = true
+	}
+	// Rewrite references to names defined in the config
+	refs := func(str string) string {
+		return nameRefRegex.ReplaceAllStringFunc(str, func(ref string) string {
+			match := nameRefRegex.FindStringSubmatch(ref)
+			if !names[match[2]] {
+				return ref
+			}
+			return "${" + match[1] + name + ":" + match[2] + match[3] + "}"
+		})
+	}
+	envRefs := func(env map[string]string) map[string]string {
+		if env == nil {
+			return nil
+		}
+		result := make(map[string]string, len(env))
+		for key, value := range env {
+			result[key] = refs(value)
+		}
+		return result
+	}
+	path := func(str string) string {
+		str = refs(str)
+		if str == "" || filepath.IsAbs(str) || strings.HasPrefix(str, "$") || strings.HasPrefix(str, "~") {
+			return str
+		}
+		return filepath.Join(dir, str)
+	}
+	paths := func(patterns []string) []string {
+		var result []string
+		for _, pattern := range patterns {
+			result = append(result, path(pattern))
+		}
+		return result
+	}
+	varNames := func(vars []string) []string {
+		var result []string
+		for _, variable := range vars {
+			if names[variable] {
+				variable = name + ":" + variable
+			}
+			result = append(result, variable)
+		}
+		return result
+	}
+	taskRef := func(str string) string {
+		if str == "" {
+			return str
+		}
+		if ref := extractRef(str); ref != "" {
+			return refs(str)
+		}
+		if names[strings.TrimPrefix(str, "#")] {
+			return strings.Replace(str, strings.TrimPrefix(str, "#"), name+":"+strings.TrimPrefix(str, "#"), 1)
+		}
+		return str
+	}
+	var commands func(cmds []Command) []Command
+	commands = func(cmds []Command) []Command {
+		var result []Command
+		for _, cmd := range cmds {
+			var pipe []string
+			for _, stage := range cmd.Pipe {
+				pipe = append(pipe, refs(stage))
+			}
+			result = append(result, Command{Cmd: refs(cmd.Cmd), Parallel: commands(cmd.Parallel), Pipe: pipe, Output: refs(cmd.Output), Env: envRefs(cmd.Env)})
+		}
+		return result
+	}
+	result := BuildMap{
+		Variable:   make(map[string]string),
+		Task:       make(map[string]Task),
+		Watch:      make(map[string]Watch),
+		Ignore:     paths(define.Ignore),
+		LiveReload: define.LiveReload,
+		Notify:     define.Notify,
+	}
+	result.RequiredVars = varNames(define.RequiredVars)
+	for variable, value := range define.Variable {
+		result.Variable[name+":"+variable] = refs(value)
+	}
+	if len(define.Prompt) > 0 {
+		result.Prompt = make(map[string]Prompt)
+		for variable, value := range define.Prompt {
+			result.Prompt[name+":"+variable] = value
+		}
+	}
+	if len(define.Alias) > 0 {
+		result.Alias = make(map[string]string)
+		for alias, task := range define.Alias {
+			result.Alias[name+":"+alias] = taskRef(task)
+		}
+	}
+	for task, value := range define.Task {
+		// Commands run in directory of the config by default
+		if value.Dir == "" {
+			value.Dir = dir
+		} else {
+			value.Dir = path(value.Dir)
+		}
+		value.Cmds = commands(value.Cmds)
+		value.When = refs(value.When)
+		value.Env = envRefs(value.Env)
+		value.Sources = paths(value.Sources)
+		value.Generates = paths(value.Generates)
+		if value.Requires != nil {
+			requires := *value.Requires
+			requires.Files = paths(requires.Files)
+			value.Requires = &requires
+		}
+		if value.Matrix != nil {
+			matrix := make(map[string][]string, len(value.Matrix))
+			for key, values := range value.Matrix {
+				for _, item := range values {
+					matrix[key] = append(matrix[key], refs(item))
+				}
+			}
+			value.Matrix = matrix
+		}
+		value.OnSuccess = taskRef(value.OnSuccess)
+		value.OnFailure = taskRef(value.OnFailure)
+		var deps []string
+		for _, dep := range value.Deps {
+			deps = append(deps, taskRef(dep))
+		}
+		value.Deps = deps
+		value.RequiredVars = varNames(value.RequiredVars)
+		var aliases []string
+		for _, alias := range value.Aliases {
+			aliases = append(aliases, name+":"+alias)
+		}
+		value.Aliases = aliases
+		result.Task[name+":"+task] = value
+	}
+	for pattern, watch := range define.Watch {
+		var refs TaskRefs
+		for _, ref := range watch.Task {
+			refs = append(refs, taskRef(ref))
+		}
+		watch.Task = refs
+		watch.Exclude = paths(watch.Exclude)
+		result.Watch[path(pattern)] = watch
+	}
+	if len(define.Schedule) > 0 {
+		result.Schedule = make(map[string]string)
+		for expr, task := range define.Schedule {
+			result.Schedule[expr] = taskRef(task)
+		}
+	}
+	return result
+}
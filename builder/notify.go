@@ -0,0 +1,59 @@
+package builder
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Notify result of watch triggered task
+func notifyResult(task string, err error, elapsed time.Duration) {
+	if opts, _ := watchOptions(); !opts.Notify {
+		return
+	}
+	message := "Task \"" + task + "\" Succeeded in " + formatElapsed(elapsed)
+	if err, ok := err.(*taskError); ok {
+		message = "Task \"" + err.task + "\"" + describeTask(err.task) + " Failed in " + formatElapsed(elapsed) + ": " + err.err.Error()
+	} else if err != nil {
+		message = "Task \"" + task + "\" Failed in " + formatElapsed(elapsed) + ": " + err.Error()
+	}
+	go func() {
+		if err := sendNotification("build.go", message); err != nil {
+			log(CLR_R, "Notify "+err.Error())
+		}
+	}()
+}
+
+// Send native desktop notification: osascript on macOS, notify-send on
+// linux, and toast by powershell on windows
+func sendNotification(title string, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := "display notification " + appleScriptQuote(message) + " with title " + appleScriptQuote(title)
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows":
+		script := `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null;` +
+			`$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02);` +
+			`$text = $xml.GetElementsByTagName('text');` +
+			`$text.Item(0).AppendChild($xml.CreateTextNode(` + powerShellQuote(title) + `)) > $null;` +
+			`$text.Item(1).AppendChild($xml.CreateTextNode(` + powerShellQuote(message) + `)) > $null;` +
+			`[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('build.go').Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", script)
+	default:
+		cmd = exec.Command("notify-send", title, message)
+	}
+	return cmd.Run()
+}
+
+// Quote string for AppleScript
+func appleScriptQuote(str string) string {
+	str = strings.Replace(str, `\`, `\\`, -1)
+	return `"` + strings.Replace(str, `"`, `\"`, -1) + `"`
+}
+
+// Quote string for PowerShell
+func powerShellQuote(str string) string {
+	return "'" + strings.Replace(str, "'", "''", -1) + "'"
+}
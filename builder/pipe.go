@@ -0,0 +1,180 @@
+package builder
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Run commands of pipe concurrently, stdout of each command is stdin of the
+// next one, stdout of last command is logged or written to output file;
+// first failed command fail the pipe
+func runPipe(run *buildRun, task string, index int, define Command, daemon bool) error {
+	if currentMap().Task[task].Container != nil || currentMap().Task[task].Remote != nil {
+		return configError("Task \"" + task + "\" Pipe Not Supported in Container or Remote")
+	}
+	dir, env, timeout, err := commandSetup(run, task, define, daemon)
+	if err != nil {
+		return err
+	}
+	var commands []string
+	for _, command := range define.Pipe {
+		command, err := run.parseVariable(command)
+		if err != nil {
+			return err
+		}
+		commands = append(commands, command)
+	}
+	output, err := run.parseVariable(define.Output)
+	if err != nil {
+		return err
+	}
+	if output != "" {
+		output = expandPath(output)
+		if !filepath.IsAbs(output) && dir != "" {
+			output = filepath.Join(dir, output)
+		}
+	}
+	// Print pipe only in dry run mode
+	if run.opts.DryRun {
+		line := strings.Join(commands, " | ")
+		if output != "" {
+			line += " > " + output
+		}
+		logTask(task, index, task, CLR_B, line+inDir(dir))
+		return nil
+	}
+	cmds := make([]*exec.Cmd, len(commands))
+	var writers []*lineWriter
+	grouped := daemon || timeout > 0 || run.group
+	for idx, command := range commands {
+		cmd, err := taskCommand(run, task, define.Pipe[idx], command)
+		if err != nil {
+			return err
+		}
+		cmd.Env = append(taskEnv(task), env...)
+		cmd.Dir = dir
+		if grouped {
+			setProcessGroup(cmd)
+		}
+		stderr := &lineWriter{task: task, index: index, color: CLR_R}
+		cmd.Stderr = stderr
+		writers = append(writers, stderr)
+		if idx > 0 {
+			if cmd.Stdin, err = cmds[idx-1].StdoutPipe(); err != nil {
+				return err
+			}
+		}
+		if verboseLog(run, task) {
+			logTask(task, index, outputPrefix(task), CLR_B, "+ "+commandLine(cmd.Args)+inDir(dir))
+		}
+		cmds[idx] = cmd
+	}
+	last := cmds[len(cmds)-1]
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		last.Stdout = file
+	} else {
+		stdout := &lineWriter{task: task, index: index, color: CLR_W}
+		last.Stdout = stdout
+		writers = append(writers, stdout)
+	}
+	execute := func() error {
+		startCMD()
+		defer finishCMD()
+		done := make(chan struct{})
+		var started []*exec.Cmd
+		defer func() {
+			processLock.Lock()
+			for _, cmd := range started {
+				delete(processes, cmd)
+				delete(daemons[task], cmd)
+				delete(daemonStatus, cmd)
+				delete(killedProcesses, cmd)
+			}
+			processLock.Unlock()
+			close(done)
+		}()
+		var err error
+		for idx, cmd := range cmds {
+			if err = cmd.Start(); err != nil {
+				break
+			}
+			if grouped {
+				attachProcess(cmd)
+				defer releaseProcess(cmd)
+			}
+			// Commands of daemon pipe could be listed and stopped like
+			// other daemons
+			processLock.Lock()
+			processes[cmd] = done
+			if daemon {
+				if daemons[task] == nil {
+					daemons[task] = make(map[*exec.Cmd]chan struct{})
+				}
+				daemons[task][cmd] = done
+				daemonStatus[cmd] = DaemonStatus{task, cmd.Process.Pid, commands[idx], time.Now()}
+			}
+			processLock.Unlock()
+			started = append(started, cmd)
+		}
+		if daemon {
+			startSession()
+		} else {
+			cancelProcesses(run, started, done)
+		}
+		// Terminate all commands if timeout
+		var timedOut int32
+		if timeout > 0 {
+			timer := time.AfterFunc(timeout, func() {
+				atomic.StoreInt32(&timedOut, 1)
+				logTask(task, index, outputPrefix(task), CLR_R, "Command Timeout After "+timeout.String())
+				for _, cmd := range started {
+					stopProcess(cmd)
+				}
+				select {
+				case <-done:
+				case <-time.After(killGrace):
+					for _, cmd := range started {
+						killProcess(cmd)
+					}
+				}
+			})
+			defer timer.Stop()
+		}
+		// Stop commands already started if the pipe is broken
+		if err != nil {
+			for _, cmd := range started {
+				killProcess(cmd)
+			}
+		}
+		for _, cmd := range started {
+			if waitErr := cmd.Wait(); waitErr != nil && err == nil {
+				err = waitErr
+			}
+		}
+		for _, writer := range writers {
+			writer.flush()
+		}
+		if atomic.LoadInt32(&timedOut) == 1 {
+			return errors.New("Timeout After " + timeout.String())
+		}
+		if err != nil && !daemon && run.canceled() {
+			return errCanceled
+		}
+		return err
+	}
+	if daemon {
+		go execute()
+		return nil
+	}
+	return execute()
+}
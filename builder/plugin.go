@@ -0,0 +1,390 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Handler of built-in command, dir and env are working directory and
+// environment of task; ctx is done when run canceled or command timeout
+type commandHandler func(ctx context.Context, run *buildRun, args []string, dir string, env []string, stdout io.Writer, stderr io.Writer) error
+
+// Built-in commands write as "name: args", like "copy: src dst"
+var commandHandlers = map[string]commandHandler{
+	"copy":     copyCommand,
+	"mkdir":    mkdirCommand,
+	"rm":       rmCommand,
+	"template": templateCommand,
+	"http-get": httpGetCommand,
+}
+
+// Prefix of external plugin executable on PATH
+const pluginPrefix = "build-go-"
+
+// Command with scheme prefix, like "copy: src dst" or "plugin:name args"
+var schemeRegex = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_-]*):(?:\s+(.*))?$`)
+var pluginRegex = regexp.MustCompile(`^plugin:([A-Za-z0-9_-]+)(?:\s+(.*))?$`)
+
+// Find handler of command with scheme prefix, built-in handler or path of
+// external executable build-go-<name>; not ok mean run command by shell
+func findPlugin(command string) (commandHandler, string, []string, bool, error) {
+	command = strings.TrimSpace(command)
+	if match := pluginRegex.FindStringSubmatch(command); match != nil {
+		path, err := exec.LookPath(pluginPrefix + match[1])
+		if err != nil {
+			return nil, "", nil, false, configError("Plugin \"" + match[1] + "\" Not Found")
+		}
+		return nil, path, splitArgs(match[2]), true, nil
+	}
+	match := schemeRegex.FindStringSubmatch(command)
+	if match == nil {
+		return nil, "", nil, false, nil
+	}
+	if handler, ok := commandHandlers[match[1]]; ok {
+		return handler, "", splitArgs(match[2]), true, nil
+	}
+	if path, err := exec.LookPath(pluginPrefix + match[1]); err == nil {
+		return nil, path, splitArgs(match[2]), true, nil
+	}
+	return nil, "", nil, false, nil
+}
+
+// Run built-in command, output is logged as command output; like process
+// command, it stop when run canceled or exceed timeout
+func runBuiltin(run *buildRun, task string, index int, handler commandHandler, args []string, dir string, env []string, timeout time.Duration, daemon bool, quiet bool) error {
+	stdout := &lineWriter{task: task, index: index, color: CLR_W, quiet: quiet}
+	stderr := &lineWriter{task: task, index: index, color: CLR_R, quiet: quiet}
+	execute := func() error {
+		startCMD()
+		defer finishCMD()
+		// Daemon keep running after run finish
+		ctx := run.ctx
+		if daemon {
+			ctx = context.Background()
+		}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		err := handler(ctx, run, args, dir, env, stdout, stderr)
+		stdout.flush()
+		stderr.flush()
+		if err != nil && !daemon && run.canceled() {
+			return errCanceled
+		}
+		if err != nil && timeout > 0 && ctx.Err() != nil {
+			err = errors.New("Timeout After " + timeout.String())
+		}
+		if err != nil && !quiet {
+			logTask(task, index, outputPrefix(task), CLR_R, err.Error())
+		}
+		return err
+	}
+	if daemon {
+		go execute()
+		return nil
+	}
+	return execute()
+}
+
+// Writer log each line as output of task
+type lineWriter struct {
+	task  string
+	index int
+	color string
+	quiet bool
+	buf   []byte
+}
+
+func (w *lineWriter) Write(data []byte) (int, error) {
+	w.buf = append(w.buf, data...)
+	for {
+		idx := strings.IndexByte(string(w.buf), '\n')
+		if idx < 0 {
+			break
+		}
+		w.logLine(strings.TrimSuffix(string(w.buf[:idx]), "\r"))
+		w.buf = w.buf[idx+1:]
+	}
+	return len(data), nil
+}
+
+// Log rest of output without line end
+func (w *lineWriter) flush() {
+	if len(w.buf) > 0 {
+		w.logLine(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *lineWriter) logLine(line string) {
+	if !w.quiet {
+		logCommand(w.task, w.index, w.color, line)
+	}
+}
+
+// Split arguments like shell, support single and double quote and backslash
+func splitArgs(str string) []string {
+	var args []string
+	var arg []rune
+	var quote rune
+	inArg, escaped := false, false
+	for _, char := range str {
+		switch {
+		case escaped:
+			arg = append(arg, char)
+			escaped = false
+		case char == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			} else {
+				arg = append(arg, char)
+			}
+		case char == '\'' || char == '"':
+			quote, inArg = char, true
+		case char == ' ' || char == '\t' || char == '\n':
+			if inArg {
+				args = append(args, string(arg))
+				arg, inArg = nil, false
+			}
+		default:
+			arg = append(arg, char)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, string(arg))
+	}
+	return args
+}
+
+// Resolve path relative to working directory of task
+func resolvePath(dir string, path string) string {
+	if dir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// Copy file or directory recursively: copy: src dst; copy into dst if it
+// is existing directory
+func copyCommand(ctx context.Context, run *buildRun, args []string, dir string, env []string, stdout io.Writer, stderr io.Writer) error {
+	if len(args) != 2 {
+		return errors.New("Usage: copy: src dst")
+	}
+	src, dst := resolvePath(dir, args[0]), resolvePath(dir, args[1])
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if dstInfo, err := os.Stat(dst); err == nil && dstInfo.IsDir() {
+		dst = filepath.Join(dst, filepath.Base(src))
+	}
+	if !info.IsDir() {
+		return copyFile(src, dst, info.Mode())
+	}
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode()|0700)
+		}
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+// Copy content and mode of file
+func copyFile(src string, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Create directories with parents: mkdir: dir...; -p is accepted and
+// always implied
+func mkdirCommand(ctx context.Context, run *buildRun, args []string, dir string, env []string, stdout io.Writer, stderr io.Writer) error {
+	var dirs []string
+	for _, arg := range args {
+		if arg != "-p" {
+			dirs = append(dirs, arg)
+		}
+	}
+	if len(dirs) == 0 {
+		return errors.New("Usage: mkdir: dir...")
+	}
+	for _, path := range dirs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(resolvePath(dir, path), 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove files or patterns: rm: [-r] [-f] path...; -r remove directory
+// recursively, -f ignore path not exist or pattern match no file
+func rmCommand(ctx context.Context, run *buildRun, args []string, dir string, env []string, stdout io.Writer, stderr io.Writer) error {
+	recursive, force := false, false
+	var paths []string
+	for _, arg := range args {
+		switch arg {
+		case "-r", "-R":
+			recursive = true
+		case "-f":
+			force = true
+		case "-rf", "-fr", "-Rf", "-fR":
+			recursive, force = true, true
+		default:
+			paths = append(paths, arg)
+		}
+	}
+	if len(paths) == 0 {
+		return errors.New("Usage: rm: [-r] [-f] path...")
+	}
+	for _, pattern := range paths {
+		pattern = resolvePath(dir, pattern)
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			if matches, err = globPath(pattern); err != nil {
+				return err
+			}
+			if len(matches) == 0 && !force {
+				return errors.New("Pattern \"" + pattern + "\" Match No File")
+			}
+		}
+		for _, path := range matches {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if _, err := os.Lstat(path); os.IsNotExist(err) {
+				if force {
+					continue
+				}
+				return err
+			}
+			remove := os.Remove
+			if recursive {
+				remove = os.RemoveAll
+			}
+			if err := remove(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Render Go template file with variables of build run: template: in out;
+// like {{ .NAME }}, with functions of template in command, env read
+// environment of task
+func templateCommand(ctx context.Context, run *buildRun, args []string, dir string, env []string, stdout io.Writer, stderr io.Writer) error {
+	if len(args) != 2 {
+		return errors.New("Usage: template: in out")
+	}
+	in, out := resolvePath(dir, args[0]), resolvePath(dir, args[1])
+	info, err := os.Stat(in)
+	if err != nil {
+		return err
+	}
+	content, err := ioutil.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	funcs := template.FuncMap{"env": func(name string) string { return lookupEnv(env, name) }}
+	tmpl, err := template.New(filepath.Base(in)).Funcs(templateFuncs).Funcs(funcs).Option("missingkey=zero").Parse(string(content))
+	if err != nil {
+		return errors.New("Template Invalid: " + err.Error())
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, run.templateData()); err != nil {
+		return errors.New("Template Invalid: " + err.Error())
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(out, buf.Bytes(), info.Mode())
+}
+
+// Download url to file, or print content: http-get: url [file]; request is
+// canceled with run, and limited by timeout of command
+func httpGetCommand(ctx context.Context, run *buildRun, args []string, dir string, env []string, stdout io.Writer, stderr io.Writer) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("Usage: http-get: url [file]")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, args[0], nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{}
+	if deadline, ok := ctx.Deadline(); ok {
+		client.Timeout = time.Until(deadline)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.New("HTTP Response " + resp.Status)
+	}
+	if len(args) == 1 {
+		_, err := io.Copy(stdout, resp.Body)
+		return err
+	}
+	path := resolvePath(dir, args[1])
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
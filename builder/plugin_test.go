@@ -0,0 +1,32 @@
+package builder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		str  string
+		want []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"a b  c", []string{"a", "b", "c"}},
+		{"a\tb\nc", []string{"a", "b", "c"}},
+		{`"a b" c`, []string{"a b", "c"}},
+		{`'a b' c`, []string{"a b", "c"}},
+		{`a"b c"d`, []string{"ab cd"}},
+		{`""`, []string{""}},
+		{`a ''`, []string{"a", ""}},
+		{`a\ b`, []string{"a b"}},
+		{`"a \"b\""`, []string{`a "b"`}},
+		{`'a \b'`, []string{`a \b`}},
+		{`"it's"`, []string{"it's"}},
+	}
+	for _, test := range tests {
+		if got := splitArgs(test.str); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", test.str, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package builder
+
+import (
+	"context"
+	"github.com/go-fsnotify/fsnotify"
+	"os"
+	"sort"
+	"time"
+)
+
+// Size and modify time of file, to detect change by polling
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Scan files match watch patterns and config files, ignored files are
+// skipped
+func scanWatchFiles() map[string]fileState {
+	files := make(map[string]fileState)
+	for _, path := range configFiles {
+		if info, err := os.Stat(path); err == nil {
+			files[path] = fileState{info.Size(), info.ModTime()}
+		}
+	}
+	for define, watch := range currentMap().Watch {
+		pattern, err := parseVariable(define)
+		if err != nil {
+			continue
+		}
+		matches, err := globPath(expandPath(pattern))
+		if err != nil {
+			continue
+		}
+		ignores := watch.ignores()
+		for _, path := range matches {
+			if matchIgnore(ignores, path) {
+				continue
+			}
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				files[path] = fileState{info.Size(), info.ModTime()}
+			}
+		}
+	}
+	return files
+}
+
+// Scan watched files on interval, handle changes as file system events
+// until context done
+func startPoll(ctx context.Context, pollInterval time.Duration) {
+	log(CLR_G, "Polling watched files every "+pollInterval.String())
+	go func() {
+		files := scanWatchFiles()
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			current := scanWatchFiles()
+			var events []fsnotify.Event
+			for path, state := range current {
+				if last, ok := files[path]; !ok {
+					events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Create})
+				} else if last != state {
+					events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Write})
+				}
+			}
+			for path := range files {
+				if _, ok := current[path]; !ok {
+					events = append(events, fsnotify.Event{Name: path, Op: fsnotify.Remove})
+				}
+			}
+			files = current
+			sort.Slice(events, func(i, j int) bool {
+				return events[i].Name < events[j].Name
+			})
+			for _, event := range events {
+				handleWatch(event)
+			}
+		}
+	}()
+}
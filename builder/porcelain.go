@@ -0,0 +1,157 @@
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Write newline delimited JSON events to stdout instead of log, for editor
+// integration
+var porcelain bool
+
+// Events written one line at a time
+var eventLock sync.Mutex
+
+// Check if events should be emitted, to stdout in porcelain mode without
+// custom logger, to subscribers of control socket, or to TUI
+func eventsEnabled() bool {
+	return porcelain && logger == nil || hasSubscribers() || tuiActive()
+}
+
+// Write event with fields as a JSON line to stdout, and to subscribers of
+// control socket or TUI; event is task_started, task_finished, command_output,
+// problem, watch_triggered, prompt or log
+func emitEvent(event string, fields map[string]interface{}) {
+	record := map[string]interface{}{
+		"event": event,
+		"time":  time.Now().Format(time.RFC3339Nano),
+	}
+	for key, value := range fields {
+		record[key] = value
+	}
+	if tuiActive() {
+		tuiEvent(record)
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	if porcelain && logger == nil {
+		eventLock.Lock()
+		fmt.Fprintln(os.Stdout, string(line))
+		eventLock.Unlock()
+	}
+	broadcastEvent(line)
+}
+
+// Log output line of command, as command_output event in porcelain mode;
+// line matched by problem matcher of task is highlighted, and also logged as
+// problem in JSON log or porcelain. Stdout of quiet task is not logged
+// unless matched, long line is truncated and lines exceed rate suppressed
+func logCommand(task string, index int, color string, line string) {
+	result, matched := matchProblem(task, line)
+	if currentMap().Task[task].Quiet && color != CLR_R && !matched {
+		return
+	}
+	if !allowLine(task, index) {
+		return
+	}
+	line = truncateLine(line)
+	if eventsEnabled() {
+		emitOutput(task, index, color, line, matched, result)
+	}
+	if porcelain && logger == nil {
+		writePlain(color, line)
+		return
+	}
+	if matched {
+		color = CLR_R
+	}
+	printTask(task, index, outputPrefix(task), color, line)
+	if matched && logger == nil && logFormat == "json" {
+		logProblem(task, index, result)
+	}
+}
+
+// Emit command_output event of output line, and problem event if matched
+func emitOutput(task string, index int, color string, line string, matched bool, result problem) {
+	stream := "stdout"
+	if color == CLR_R {
+		stream = "stderr"
+	}
+	fields := map[string]interface{}{"task": task, "stream": stream, "line": line}
+	if index >= 0 {
+		fields["index"] = index
+	}
+	emitEvent("command_output", fields)
+	if matched {
+		problemFields := map[string]interface{}{
+			"task":     task,
+			"file":     result.File,
+			"line":     result.Line,
+			"column":   result.Column,
+			"severity": result.Severity,
+			"message":  result.Message,
+		}
+		if index >= 0 {
+			problemFields["index"] = index
+		}
+		emitEvent("problem", problemFields)
+	}
+}
+
+// Log as log event in porcelain mode, level is log type in lower case
+func logEvent(task string, index int, outputType string, info interface{}) {
+	fields := map[string]interface{}{"level": strings.ToLower(outputType), "message": fmt.Sprint(info)}
+	if task != "" {
+		fields["task"] = task
+	}
+	if index >= 0 {
+		fields["index"] = index
+	}
+	emitEvent("log", fields)
+}
+
+// Write line without color to log file and tail for webhook, not printed
+func writePlain(color string, line string) {
+	plain := logType(color) + ": " + line
+	if logOutput != nil {
+		logOutput.writeLine(plain)
+	}
+	tailLog(plain)
+}
+
+// Emit task_started event
+func emitTaskStarted(task string) {
+	if eventsEnabled() {
+		emitEvent("task_started", map[string]interface{}{"task": task})
+	}
+}
+
+// Emit task_finished event with status of summary and elapsed milliseconds
+func emitTaskFinished(task string, status string, elapsed time.Duration) {
+	if eventsEnabled() {
+		emitEvent("task_finished", map[string]interface{}{
+			"task":       task,
+			"status":     status,
+			"elapsed_ms": elapsed.Seconds() * 1000,
+		})
+	}
+}
+
+// Emit watch_triggered event of changed file
+func emitWatchTriggered(trigger watchTrigger) {
+	if eventsEnabled() {
+		emitEvent("watch_triggered", map[string]interface{}{
+			"pattern":  trigger.pattern,
+			"task":     trigger.task,
+			"file":     trigger.change.file,
+			"change":   trigger.change.event,
+			"old_file": trigger.change.oldFile,
+		})
+	}
+}
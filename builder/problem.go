@@ -0,0 +1,148 @@
+package builder
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Problem matcher parse diagnostics from command output by regex, groups
+// of file, line, column, severity and message are 1-based index, 0 mean
+// not matched; or write as name of built-in matcher go, gcc or tsc
+type ProblemMatcher struct {
+	Name     string
+	Pattern  string
+	File     int
+	Line     int
+	Column   int
+	Severity int
+	Message  int
+}
+
+// Support built-in matcher name as problem matcher define
+func (matcher *ProblemMatcher) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&matcher.Name); err == nil {
+		return nil
+	}
+	type matcherDefine ProblemMatcher
+	return unmarshal((*matcherDefine)(matcher))
+}
+
+// Support built-in matcher name as problem matcher define
+func (matcher *ProblemMatcher) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &matcher.Name); err == nil {
+		return nil
+	}
+	type matcherDefine ProblemMatcher
+	return json.Unmarshal(data, (*matcherDefine)(matcher))
+}
+
+// Support built-in matcher name as problem matcher define
+func (matcher *ProblemMatcher) UnmarshalTOML(value interface{}) error {
+	return unmarshalTOML(value, matcher)
+}
+
+// Built-in matchers of go build and vet, gcc or clang, and tsc
+var problemMatchers = map[string]ProblemMatcher{
+	"go":  {Pattern: `^\s*([^\s:]+\.go):(\d+)(?::(\d+))?: (.*)$`, File: 1, Line: 2, Column: 3, Message: 4},
+	"gcc": {Pattern: `^(.+?):(\d+):(\d+): (?:fatal )?(error|warning|note): (.*)$`, File: 1, Line: 2, Column: 3, Severity: 4, Message: 5},
+	"tsc": {Pattern: `^(.+?)\((\d+),(\d+)\): (error|warning) (.*)$`, File: 1, Line: 2, Column: 3, Severity: 4, Message: 5},
+}
+
+// Diagnostic parsed from command output, severity default is error
+type problem struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Compiled patterns of matchers, keep by pattern
+var problemRegexps = make(map[string]*regexp.Regexp)
+var problemLock sync.Mutex
+
+// Get matcher define of built-in name, and its compiled pattern
+func (matcher ProblemMatcher) compile() (ProblemMatcher, *regexp.Regexp, error) {
+	if matcher.Name != "" {
+		builtin, ok := problemMatchers[strings.ToLower(matcher.Name)]
+		if !ok {
+			return matcher, nil, configError("Problem Matcher \"" + matcher.Name + "\" Not Found")
+		}
+		matcher = builtin
+	}
+	problemLock.Lock()
+	defer problemLock.Unlock()
+	if regex, ok := problemRegexps[matcher.Pattern]; ok {
+		return matcher, regex, nil
+	}
+	regex, err := regexp.Compile(matcher.Pattern)
+	if err != nil {
+		return matcher, nil, configError("Problem Matcher Pattern Invalid: " + err.Error())
+	}
+	if matcher.File == 0 || matcher.File > regex.NumSubexp() {
+		return matcher, nil, configError("Problem Matcher File Group Invalid")
+	}
+	problemRegexps[matcher.Pattern] = regex
+	return matcher, regex, nil
+}
+
+// Match output line of task by its problem matchers, file is relative to
+// directory of task
+func matchProblem(task string, line string) (problem, bool) {
+	define := currentMap().Task[task]
+	for _, matcher := range define.Problems {
+		matcher, regex, err := matcher.compile()
+		if err != nil {
+			continue
+		}
+		groups := regex.FindStringSubmatch(line)
+		if groups == nil {
+			continue
+		}
+		group := func(idx int) string {
+			if idx <= 0 || idx >= len(groups) {
+				return ""
+			}
+			return strings.TrimSpace(groups[idx])
+		}
+		result := problem{
+			File:     group(matcher.File),
+			Severity: strings.ToLower(group(matcher.Severity)),
+			Message:  group(matcher.Message),
+		}
+		result.Line, _ = strconv.Atoi(group(matcher.Line))
+		result.Column, _ = strconv.Atoi(group(matcher.Column))
+		if result.Severity == "" {
+			result.Severity = "error"
+		}
+		if result.Message == "" {
+			result.Message = strings.TrimSpace(line)
+		}
+		if dir, err := parseVariable(define.Dir); err == nil && dir != "" && !filepath.IsAbs(result.File) {
+			result.File = filepath.Join(expandPath(dir), result.File)
+		}
+		return result, true
+	}
+	return problem{}, false
+}
+
+// Log problem as record of JSON log format
+func logProblem(task string, index int, result problem) {
+	record := logRecord{
+		Level:   "problem",
+		Task:    task,
+		Message: result.Message,
+		Time:    time.Now().Format(time.RFC3339Nano),
+		Problem: &result,
+	}
+	if index >= 0 {
+		record.Index = &index
+	}
+	line, _ := json.Marshal(record)
+	writeLog(string(line), string(line))
+}
@@ -0,0 +1,56 @@
+//go:build !windows
+// +build !windows
+
+package builder
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Run command in new process group, so its children could be killed together
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// Process group is set before start, nothing to do after start
+func attachProcess(cmd *exec.Cmd) {
+}
+
+// Process group need no release
+func releaseProcess(cmd *exec.Cmd) {
+}
+
+// Send signal to process group of command, or process only if not a group
+func signalProcess(cmd *exec.Cmd, sig syscall.Signal) error {
+	if err := syscall.Kill(-cmd.Process.Pid, sig); err == nil {
+		return nil
+	}
+	return cmd.Process.Signal(sig)
+}
+
+// Ask process to exit
+func stopProcess(cmd *exec.Cmd) error {
+	return signalProcess(cmd, syscall.SIGTERM)
+}
+
+// Get command line passed to process, quoted for shell
+func commandLine(args []string) string {
+	return quoteArgs(args)
+}
+
+// Force process to exit
+func killProcess(cmd *exec.Cmd) error {
+	return signalProcess(cmd, syscall.SIGKILL)
+}
+
+// Lock file exclusively without wait, false if locked by other process;
+// released when process exit
+func lockFile(file *os.File) (bool, error) {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,132 @@
+package builder
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// Job object limit to kill all processes in job when its handle closed,
+// and access right to assign process to job
+const (
+	jobObjectExtendedLimitInformation = 9
+	jobObjectLimitKillOnJobClose      = 0x2000
+	processSetQuota                   = 0x0100
+	lockfileFailImmediately           = 0x1
+	lockfileExclusiveLock             = 0x2
+	errorLockViolation                = syscall.Errno(33)
+)
+
+var (
+	procCreateJobObject          = kernel32.NewProc("CreateJobObjectW")
+	procSetInformationJobObject  = kernel32.NewProc("SetInformationJobObject")
+	procAssignProcessToJobObject = kernel32.NewProc("AssignProcessToJobObject")
+	procTerminateJobObject       = kernel32.NewProc("TerminateJobObject")
+	procLockFileEx               = kernel32.NewProc("LockFileEx")
+)
+
+// JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+type jobLimitInfo struct {
+	PerProcessUserTimeLimit int64
+	PerJobUserTimeLimit     int64
+	LimitFlags              uint32
+	MinimumWorkingSetSize   uintptr
+	MaximumWorkingSetSize   uintptr
+	ActiveProcessLimit      uint32
+	Affinity                uintptr
+	PriorityClass           uint32
+	SchedulingClass         uint32
+	IoInfo                  [6]uint64
+	ProcessMemoryLimit      uintptr
+	JobMemoryLimit          uintptr
+	PeakProcessMemoryUsed   uintptr
+	PeakJobMemoryUsed       uintptr
+}
+
+// Job objects of grouped commands, children of command are in same job
+var jobs = make(map[*exec.Cmd]syscall.Handle)
+var jobLock sync.Mutex
+
+// Windows has no process group, use job object after start instead
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// Assign started process to new job object, processes it create later are
+// also in the job
+func attachProcess(cmd *exec.Cmd) {
+	job, _, _ := procCreateJobObject.Call(0, 0)
+	if job == 0 {
+		return
+	}
+	info := jobLimitInfo{LimitFlags: jobObjectLimitKillOnJobClose}
+	procSetInformationJobObject.Call(job, jobObjectExtendedLimitInformation, uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info))
+	process, err := syscall.OpenProcess(syscall.PROCESS_TERMINATE|processSetQuota, false, uint32(cmd.Process.Pid))
+	if err != nil {
+		syscall.CloseHandle(syscall.Handle(job))
+		return
+	}
+	defer syscall.CloseHandle(process)
+	if ret, _, _ := procAssignProcessToJobObject.Call(job, uintptr(process)); ret == 0 {
+		syscall.CloseHandle(syscall.Handle(job))
+		return
+	}
+	jobLock.Lock()
+	jobs[cmd] = syscall.Handle(job)
+	jobLock.Unlock()
+}
+
+// Close job object of command, kill processes left in the job
+func releaseProcess(cmd *exec.Cmd) {
+	jobLock.Lock()
+	job, ok := jobs[cmd]
+	delete(jobs, cmd)
+	jobLock.Unlock()
+	if ok {
+		syscall.CloseHandle(job)
+	}
+}
+
+// Windows has no SIGTERM, just kill the process
+func stopProcess(cmd *exec.Cmd) error {
+	return killProcess(cmd)
+}
+
+// Get command line passed to process, quoted the same as exec package
+func commandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for idx, arg := range args {
+		quoted[idx] = syscall.EscapeArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Force process to exit, with all processes in its job
+func killProcess(cmd *exec.Cmd) error {
+	jobLock.Lock()
+	job, ok := jobs[cmd]
+	jobLock.Unlock()
+	if ok {
+		if ret, _, err := procTerminateJobObject.Call(uintptr(job), 1); ret == 0 {
+			return err
+		}
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// Lock first byte of file exclusively without wait, false if locked by
+// other process; released when process exit
+func lockFile(file *os.File) (bool, error) {
+	var overlapped syscall.Overlapped
+	ok, _, err := procLockFileEx.Call(file.Fd(), lockfileFailImmediately|lockfileExclusiveLock, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if ok != 0 {
+		return true, nil
+	}
+	if err == errorLockViolation {
+		return false, nil
+	}
+	return false, err
+}
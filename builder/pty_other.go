@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package builder
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// Pseudo terminal is only supported on linux and darwin
+func startPty(cmd *exec.Cmd) (*os.File, *os.File, error) {
+	return nil, nil, errors.New("PTY Not Supported on This Platform")
+}
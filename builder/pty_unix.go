@@ -0,0 +1,43 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package builder
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// Size of terminal window
+type winsize struct {
+	rows uint16
+	cols uint16
+	x    uint16
+	y    uint16
+}
+
+// Send ioctl request to file descriptor
+func ioctl(fd uintptr, request uintptr, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Run command under pseudo terminal as its controlling terminal, in new
+// session; size follow terminal of stdout, or 80x24. Output of command is
+// read from master, slave should be closed after command start
+func startPty(cmd *exec.Cmd) (*os.File, *os.File, error) {
+	master, slave, err := openPty()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := winsize{rows: 24, cols: 80}
+	ioctl(os.Stdout.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&size)))
+	ioctl(slave.Fd(), syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&size)))
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	return master, slave, nil
+}
@@ -0,0 +1,78 @@
+package builder
+
+import (
+	"github.com/go-fsnotify/fsnotify"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Files read when load config: config file, include and namespace files,
+// user config; with env file and ignore file, watched to reload config
+var configFiles []string
+var loadingFiles []string
+
+// Delay reload until config files not change in a while, editors may write
+// file more than once when save
+const reloadDelay = 200 * time.Millisecond
+
+var reloadTimer *time.Timer
+var reloadLock sync.Mutex
+
+// Remember files of loaded config, include env file and ignore file if
+// exists
+func setConfigFiles(files []string) {
+	for _, file := range []string{envFile, ".env", ignoreFile} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(expandPath(file)); err == nil {
+			files = append(files, expandPath(file))
+		}
+	}
+	configFiles = files
+}
+
+// Check if path is one of files of loaded config
+func isConfigFile(name string) bool {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return false
+	}
+	for _, file := range configFiles {
+		if path, err := filepath.Abs(file); err == nil && path == abs {
+			return true
+		}
+	}
+	return false
+}
+
+// Add directories of config files to watcher
+func watchConfigFiles() {
+	for _, file := range configFiles {
+		dir := filepath.Dir(file)
+		if addWatchDir(dir) {
+			if err := watcher.Add(dir); err != nil {
+				log(CLR_R, err.Error())
+			}
+		}
+	}
+}
+
+// Reload config when config file changed, after no more change in delay
+func handleConfigChange(event fsnotify.Event) {
+	if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 || !isConfigFile(event.Name) {
+		return
+	}
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+	if reloadTimer != nil && reloadTimer.Stop() {
+		reloadTimer.Reset(reloadDelay)
+		return
+	}
+	reloadTimer = time.AfterFunc(reloadDelay, func() {
+		log(CLR_G, "Config Changed, Reloading")
+		reloadConfig()
+	})
+}
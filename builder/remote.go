@@ -0,0 +1,99 @@
+package builder
+
+import (
+	"encoding/json"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Remote machine to run commands of task by ssh, write as host or object
+type Remote struct {
+	// Host name, could be user@host
+	Host string
+	// User to login, default by ssh config
+	User string
+	// Private key file, default by ssh config
+	Key string
+	// Port of ssh, default by ssh config
+	Port int
+	// Working directory on remote machine, dir of task is relative to it
+	Dir string
+}
+
+// Support host as remote define
+func (remote *Remote) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&remote.Host); err == nil {
+		return nil
+	}
+	type remoteDefine Remote
+	return unmarshal((*remoteDefine)(remote))
+}
+
+// Support host as remote define
+func (remote *Remote) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &remote.Host); err == nil {
+		return nil
+	}
+	type remoteDefine Remote
+	return json.Unmarshal(data, (*remoteDefine)(remote))
+}
+
+// Support host as remote define
+func (remote *Remote) UnmarshalTOML(value interface{}) error {
+	return unmarshalTOML(value, remote)
+}
+
+// Build ssh command which run command on remote machine by shell, in dir
+// of task and with env; ssh run in batch mode since no stdin
+func remoteCommand(run *buildRun, remote *Remote, command string, dir string, env []string) (*exec.Cmd, error) {
+	var err error
+	define := *remote
+	for _, value := range []*string{&define.Host, &define.User, &define.Key, &define.Dir} {
+		if *value, err = run.parseVariable(*value); err != nil {
+			return nil, err
+		}
+	}
+	if define.Host == "" {
+		return nil, configError("Remote Host Required")
+	}
+	args := []string{"-o", "BatchMode=yes"}
+	if define.Key != "" {
+		args = append(args, "-i", expandPath(define.Key))
+	}
+	if define.Port > 0 {
+		args = append(args, "-p", strconv.Itoa(define.Port))
+	}
+	if define.User != "" {
+		args = append(args, "-l", define.User)
+	}
+	// Remote shell parse the script, quote every argument in it
+	workdir := define.Dir
+	if dir != "" {
+		if workdir == "" || strings.HasPrefix(dir, "/") {
+			workdir = dir
+		} else {
+			workdir = path.Join(workdir, dir)
+		}
+	}
+	var script []string
+	if workdir != "" {
+		script = append(script, "cd", shellQuote(workdir), "&&")
+	}
+	script = append(script, "exec")
+	if len(env) > 0 {
+		script = append(script, "env")
+		for _, pair := range env {
+			script = append(script, shellQuote(pair))
+		}
+	}
+	script = append(script, "sh", "-c", shellQuote(command))
+	args = append(args, define.Host, strings.Join(script, " "))
+	return exec.Command("ssh", args...), nil
+}
+
+// Quote argument for POSIX shell
+func shellQuote(arg string) string {
+	return "'" + strings.Replace(arg, "'", "'\\''", -1) + "'"
+}
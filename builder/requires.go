@@ -0,0 +1,77 @@
+package builder
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Preconditions of task, checked before deps and commands run
+type Requires struct {
+	// Commands must be found in PATH
+	Commands []string
+	// Environment variables must be set
+	Env []string
+	// File patterns must match file
+	Files []string
+}
+
+// Get variables without value in names, built-in and matrix variables of
+// run are checked if run is not nil, others by lookup
+func missingVars(run *buildRun, names []string, lookup func(string) (string, bool)) []string {
+	var missing []string
+	for _, name := range names {
+		if run != nil {
+			value, ok := run.matrix[name]
+			if !ok {
+				value, ok = run.vars[name]
+			}
+			if ok {
+				if value == "" {
+					missing = append(missing, name)
+				}
+				continue
+			}
+		}
+		if value, ok := lookup(name); !ok || value == "" {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// Check preconditions of task, report all unmet ones in one error
+func checkRequires(run *buildRun, task string, requires *Requires) error {
+	if requires == nil {
+		return nil
+	}
+	var unmet []string
+	for _, name := range requires.Commands {
+		name, err := run.parseVariable(name)
+		if err != nil {
+			return err
+		}
+		if _, err := exec.LookPath(name); err != nil {
+			unmet = append(unmet, "Command \""+name+"\" Not Found")
+		}
+	}
+	for _, name := range requires.Env {
+		if _, ok := os.LookupEnv(name); !ok {
+			unmet = append(unmet, "Environment Variable \""+name+"\" Not Set")
+		}
+	}
+	for _, pattern := range requires.Files {
+		pattern, err := run.parseVariable(pattern)
+		if err != nil {
+			return err
+		}
+		if matches, err := globPath(expandPath(pattern)); err != nil || len(matches) == 0 {
+			unmet = append(unmet, "File \""+pattern+"\" Not Found")
+		}
+	}
+	if len(unmet) > 0 {
+		return errors.New("Task \"" + task + "\"" + describeTask(task) + " Requirements Not Met: " + strings.Join(unmet, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package builder
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cron expression with fields minute, hour, day of month, month and day of
+// week; each field is set of matched values
+type cronSchedule struct {
+	fields [5]map[int]bool
+	// Day of month and day of week restricted, match either of them
+	anyDay bool
+}
+
+// Parsed schedule define, replaced when reload config
+var schedules map[string]*cronSchedule
+var scheduleLock sync.Mutex
+
+// Range of cron fields, day of week 7 is Sunday too
+var cronRanges = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// Parse cron expression like "*/5 * * * *", field support *, n, a-b, a,b
+// and step like */n or a-b/n
+func parseCron(expr string) (*cronSchedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, configError("Schedule \"" + expr + "\" Invalid, Expect 5 Fields")
+	}
+	schedule := &cronSchedule{}
+	for idx, part := range parts {
+		field, err := parseCronField(part, cronRanges[idx][0], cronRanges[idx][1])
+		if err != nil {
+			return nil, configError("Schedule \"" + expr + "\" Invalid: " + err.Error())
+		}
+		schedule.fields[idx] = field
+	}
+	if dow := schedule.fields[4]; dow[7] {
+		dow[0] = true
+		delete(dow, 7)
+	}
+	schedule.anyDay = parts[2] != "*" && parts[4] != "*"
+	return schedule, nil
+}
+
+// Parse a cron field to set of values in range
+func parseCronField(field string, min int, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(item, "/"); idx >= 0 {
+			var err error
+			if step, err = strconv.Atoi(item[idx+1:]); err != nil || step <= 0 {
+				return nil, configError("Step \"" + item + "\" Invalid")
+			}
+			item = item[:idx]
+		}
+		start, end := min, max
+		if item != "*" {
+			bounds := strings.SplitN(item, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, configError("Value \"" + item + "\" Invalid")
+			}
+			end = start
+			if len(bounds) == 2 {
+				if end, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, configError("Value \"" + item + "\" Invalid")
+				}
+			} else if step > 1 {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, configError("Value \"" + item + "\" Out Of Range")
+		}
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+// Check if time match the schedule, in minute precision
+func (schedule *cronSchedule) match(t time.Time) bool {
+	if !schedule.fields[0][t.Minute()] || !schedule.fields[1][t.Hour()] || !schedule.fields[3][int(t.Month())] {
+		return false
+	}
+	dom := schedule.fields[2][t.Day()]
+	dow := schedule.fields[4][int(t.Weekday())]
+	if schedule.anyDay {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// Parse schedule define, check expressions and task references
+func (config BuildMap) parseSchedules() (map[string]*cronSchedule, error) {
+	parsed := make(map[string]*cronSchedule)
+	for expr, task := range config.Schedule {
+		schedule, err := parseCron(expr)
+		if err != nil {
+			return nil, err
+		}
+		if extractRef(task) == "" {
+			return nil, configError("Schedule \"" + expr + "\" Task Reference Invalid")
+		}
+		parsed[expr] = schedule
+	}
+	return parsed, nil
+}
+
+// Replace parsed schedule define
+func setSchedules(parsed map[string]*cronSchedule) {
+	scheduleLock.Lock()
+	schedules = parsed
+	scheduleLock.Unlock()
+}
+
+// Run scheduled tasks at start of each matched minute, until context done
+func startSchedule(ctx context.Context) {
+	go func() {
+		for {
+			now := time.Now()
+			next := now.Truncate(time.Minute).Add(time.Minute)
+			select {
+			case <-time.After(next.Sub(now)):
+			case <-ctx.Done():
+				return
+			}
+			var tasks []string
+			scheduleLock.Lock()
+			for expr, schedule := range schedules {
+				if schedule.match(next) {
+					tasks = append(tasks, extractRef(currentMap().Schedule[expr]))
+				}
+			}
+			scheduleLock.Unlock()
+			sort.Strings(tasks)
+			opts, _ := watchOptions()
+			for _, task := range tasks {
+				triggerTask(task, nil, opts.WatchPolicy)
+			}
+		}
+	}()
+}
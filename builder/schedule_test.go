@@ -0,0 +1,68 @@
+package builder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	// 2024-01-07 is Sunday
+	sunday := time.Date(2024, 1, 7, 10, 30, 0, 0, time.Local)
+	monday := sunday.AddDate(0, 0, 1)
+	tests := []struct {
+		expr string
+		at   time.Time
+		want bool
+	}{
+		{"* * * * *", sunday, true},
+		{"30 10 * * *", sunday, true},
+		{"31 10 * * *", sunday, false},
+		{"*/15 * * * *", sunday, true},
+		{"*/20 * * * *", sunday, false},
+		{"0-30/10 9-11 * * *", sunday, true},
+		{"30 10 7 1 *", sunday, true},
+		{"30 10 8 1 *", sunday, false},
+		{"30 10 * * 0", sunday, true},
+		{"30 10 * * 7", sunday, true},
+		{"30 10 * * 7", monday, false},
+		{"30 10 * * 1-5", monday, true},
+		{"30 10 * * 5-7", sunday, true},
+		{"30 10 * * 1,3", sunday, false},
+		// Day of month or day of week if both restricted
+		{"30 10 1 * 0", sunday, true},
+		{"30 10 8 * 0", monday, true},
+		{"30 10 9 * 0", monday, false},
+	}
+	for _, test := range tests {
+		schedule, err := parseCron(test.expr)
+		if err != nil {
+			t.Errorf("parseCron(%q) error: %s", test.expr, err)
+			continue
+		}
+		if got := schedule.match(test.at); got != test.want {
+			t.Errorf("parseCron(%q).match(%s) = %v, want %v", test.expr, test.at.Format("Mon 15:04"), got, test.want)
+		}
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1-b * * * *",
+	}
+	for _, expr := range tests {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) expect error", expr)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package builder
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// Static file server define of task, serve dir on host:port
+type Serve struct {
+	// Directory of files, relative to dir of task, default is current dir
+	Dir string
+	// Port to listen, default is 8080
+	Port int
+	// Host to listen, default is all interfaces
+	Host string
+}
+
+// Running static file servers, by task
+var servers = make(map[string]*http.Server)
+var serverLock sync.Mutex
+
+// Start static file server of task, restart it if already running
+func startServer(run *buildRun, task string, define *Serve) error {
+	dir := define.Dir
+	if dir == "" {
+		dir = "."
+	}
+	dir, err := run.parseVariable(dir)
+	if err != nil {
+		return err
+	}
+	dir = expandPath(dir)
+	if taskDir := currentMap().Task[task].Dir; taskDir != "" && !filepath.IsAbs(dir) {
+		if taskDir, err = run.parseVariable(taskDir); err != nil {
+			return err
+		}
+		dir = filepath.Join(expandPath(taskDir), dir)
+	}
+	port := define.Port
+	if port == 0 {
+		port = 8080
+	}
+	addr := net.JoinHostPort(define.Host, strconv.Itoa(port))
+	if run.opts.DryRun {
+		logTask(task, -1, task, CLR_B, "serve "+dir+" on "+addr)
+		return nil
+	}
+	stopServer(task)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: http.FileServer(http.Dir(dir))}
+	serverLock.Lock()
+	servers[task] = server
+	serverLock.Unlock()
+	logTask(task, -1, "", CLR_G, "Serving "+dir+" on "+addr)
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logTask(task, -1, "", CLR_R, err.Error())
+		}
+	}()
+	return nil
+}
+
+// Stop static file server of task, wait for active requests in grace period
+func stopServer(task string) {
+	serverLock.Lock()
+	server := servers[task]
+	delete(servers, task)
+	serverLock.Unlock()
+	if server == nil {
+		return
+	}
+	logTask(task, -1, "", CLR_G, task+" RESTARTING")
+	shutdownServer(server)
+}
+
+// Stop all static file servers
+func stopServers() {
+	serverLock.Lock()
+	running := servers
+	servers = make(map[string]*http.Server)
+	serverLock.Unlock()
+	var group sync.WaitGroup
+	for _, server := range running {
+		group.Add(1)
+		go func(server *http.Server) {
+			defer group.Done()
+			shutdownServer(server)
+		}(server)
+	}
+	group.Wait()
+}
+
+// Shutdown server gracefully, close it if not finish in grace period
+func shutdownServer(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), killGrace)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+	}
+}
+
+// Check if any static file server running
+func hasServers() bool {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+	return len(servers) > 0
+}
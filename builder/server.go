@@ -0,0 +1,120 @@
+package builder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Address of HTTP control server, empty mean not serve
+var serveAddr string
+
+// Status of build, response of GET /status
+type serveStatus struct {
+	Running int            `json:"running"`
+	Daemons map[string]int `json:"daemons"`
+	Last    []string       `json:"last"`
+}
+
+// Start HTTP control server: POST /task/{name} run the task, add ?wait=true
+// to wait for result; GET /status get running commands and daemon tasks;
+// also endpoints of daemon supervision. Requests need Bearer token of
+// session in .build/session.json, address of only port bind to localhost
+func startServe(addr string) {
+	if !strings.Contains(addr, ":") {
+		addr = ":" + addr
+	}
+	if strings.HasPrefix(addr, ":") {
+		addr = "127.0.0.1" + addr
+	}
+	startSession()
+	token := currentSessionToken()
+	if token == "" {
+		log(CLR_R, "Serve Session Not Started")
+		shutdown(1)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/task/", serveTask)
+	mux.HandleFunc("/status", serveStatusInfo)
+	handleDaemons(mux)
+	log(CLR_G, "Serving control on "+addr+", token in "+sessionFile)
+	go func() {
+		if err := http.ListenAndServe(addr, requireToken(token, rejectCrossOrigin(mux))); err != nil {
+			log(CLR_R, "Serve "+err.Error())
+			shutdown(1)
+		}
+	}()
+}
+
+// Reject POST from page of other origin, Origin of browser must match host
+// of request
+func rejectCrossOrigin(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && r.Method == http.MethodPost {
+			if parsed, err := url.Parse(origin); err != nil || parsed.Host != r.Host {
+				serveJSON(w, http.StatusForbidden, map[string]string{"error": "Cross Origin Request Rejected"})
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Run task by request
+func serveTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		serveJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method Not Allowed"})
+		return
+	}
+	task := resolveAlias(strings.TrimPrefix(r.URL.Path, "/task/"))
+	if _, ok := currentMap().Task[task]; !ok {
+		serveJSON(w, http.StatusNotFound, map[string]string{"error": "Task \"" + task + "\" Not Found"})
+		return
+	}
+	run := newBuildRun("")
+	run.trigger = "server"
+	if r.URL.Query().Get("wait") != "true" {
+		go func() {
+			handleError(runTaskLimited(run, task))
+		}()
+		serveJSON(w, http.StatusAccepted, map[string]string{"task": task})
+		return
+	}
+	if err := runTaskLimited(run, task); err != nil {
+		serveJSON(w, http.StatusInternalServerError, map[string]string{"task": task, "error": err.Error()})
+		handleError(err)
+		return
+	}
+	serveJSON(w, http.StatusOK, map[string]string{"task": task})
+}
+
+// Response status of build
+func serveStatusInfo(w http.ResponseWriter, r *http.Request) {
+	serveJSON(w, http.StatusOK, buildStatus())
+}
+
+// Get running commands, daemon tasks and tasks of last build run
+func buildStatus() serveStatus {
+	status := serveStatus{Daemons: make(map[string]int)}
+	processLock.Lock()
+	status.Running = len(processes)
+	for task, cmds := range daemons {
+		if len(cmds) > 0 {
+			status.Daemons[task] = len(cmds)
+		}
+	}
+	processLock.Unlock()
+	lastTasksLock.Lock()
+	status.Last = lastTasks
+	lastTasksLock.Unlock()
+	return status
+}
+
+// Write value as JSON response
+func serveJSON(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,63 @@
+package builder
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Shells could run commands of task, arguments before command; none run
+// command directly as argv, split like shell without expand
+var shells = map[string][]string{
+	"sh":         {"/bin/sh", "-c"},
+	"bash":       {"bash", "-c"},
+	"zsh":        {"zsh", "-c"},
+	"cmd":        {"cmd", "/C"},
+	"powershell": {"powershell", "-NoProfile", "-NonInteractive", "-Command"},
+	"pwsh":       {"pwsh", "-NoProfile", "-NonInteractive", "-Command"},
+	"none":       nil,
+}
+
+// Check shell of task is supported, empty is default shell of platform
+func checkShell(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := shells[strings.ToLower(name)]; !ok {
+		return configError("Shell \"" + name + "\" Not Supported")
+	}
+	return nil
+}
+
+// Get exec command of task by its shell; in none shell, variables are
+// parsed in each argument split from raw command, so file names with space
+// or shell characters are passed as is
+func taskCommand(run *buildRun, task string, raw string, command string) (*exec.Cmd, error) {
+	name := strings.ToLower(currentMap().Task[task].Shell)
+	if name == "" {
+		return shellCommand(command), nil
+	}
+	if err := checkShell(name); err != nil {
+		return nil, err
+	}
+	if name != "none" {
+		shell := shells[name]
+		if name == "powershell" && runtime.GOOS != "windows" {
+			shell = shells["pwsh"]
+		}
+		return exec.Command(shell[0], append(shell[1:], command)...), nil
+	}
+	var args []string
+	for _, arg := range splitArgs(raw) {
+		arg, err := run.parseVariable(arg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if len(args) == 0 {
+		return nil, errors.New("Command Is Empty")
+	}
+	return exec.Command(args[0], args[1:]...), nil
+}
@@ -0,0 +1,252 @@
+package builder
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Path of control socket, empty mean not listen
+var socketPath string
+
+// Request of control socket, a JSON object per line; method is list,
+// status, daemons, run, stop, restart, subscribe or unsubscribe
+type socketRequest struct {
+	ID     interface{} `json:"id,omitempty"`
+	Method string      `json:"method"`
+	Task   string      `json:"task,omitempty"`
+	// Wait run finish before response
+	Wait bool `json:"wait,omitempty"`
+}
+
+// Response of request with same id, result or error
+type socketResponse struct {
+	ID     interface{} `json:"id,omitempty"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// Task in response of list
+type socketTask struct {
+	Name    string   `json:"name"`
+	Desc    string   `json:"desc,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+	Deps    []string `json:"deps,omitempty"`
+}
+
+// Connection of control socket, lines are queued and written in order;
+// subscribed one receive events like --porcelain
+type socketClient struct {
+	conn       net.Conn
+	lines      chan []byte
+	subscribed bool
+}
+
+// Lines queued for each client, events are dropped if it is too slow
+const socketQueue = 1024
+
+// Connected clients of control socket, and count of subscribed ones
+var socketClients = make(map[*socketClient]bool)
+var socketLock sync.Mutex
+var subscribers int32
+
+// Listen control socket on path, unix domain socket which is also
+// supported by windows 10; stale socket file is removed
+func startSocket(path string) error {
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	log(CLR_G, "Serving control socket on "+path)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSocket(conn)
+		}
+	}()
+	return nil
+}
+
+// Remove socket file when exit
+func removeSocket() {
+	if socketPath != "" {
+		os.Remove(socketPath)
+	}
+}
+
+// Read requests of connection until closed
+func serveSocket(conn net.Conn) {
+	client := &socketClient{conn: conn, lines: make(chan []byte, socketQueue)}
+	socketLock.Lock()
+	socketClients[client] = true
+	socketLock.Unlock()
+	go func() {
+		for line := range client.lines {
+			if _, err := conn.Write(line); err != nil {
+				conn.Close()
+			}
+		}
+	}()
+	defer func() {
+		socketLock.Lock()
+		delete(socketClients, client)
+		close(client.lines)
+		if client.subscribed {
+			atomic.AddInt32(&subscribers, -1)
+		}
+		socketLock.Unlock()
+		conn.Close()
+	}()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var request socketRequest
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			client.send(socketResponse{Error: "Request Invalid: " + err.Error()})
+			continue
+		}
+		if request.Method == "run" && request.Wait {
+			// Not block other requests and events of connection
+			go client.handle(request)
+			continue
+		}
+		client.handle(request)
+	}
+}
+
+// Queue line for client, drop it if queue is full
+func (client *socketClient) write(line []byte) {
+	socketLock.Lock()
+	defer socketLock.Unlock()
+	if !socketClients[client] {
+		return
+	}
+	buf := make([]byte, len(line)+1)
+	copy(buf, line)
+	buf[len(line)] = '\n'
+	select {
+	case client.lines <- buf:
+	default:
+	}
+}
+
+// Queue response for client
+func (client *socketClient) send(response socketResponse) {
+	line, err := json.Marshal(response)
+	if err == nil {
+		client.write(line)
+	}
+}
+
+// Handle request of client, response result or error
+func (client *socketClient) handle(request socketRequest) {
+	result, err := socketResult(client, request)
+	response := socketResponse{ID: request.ID, Result: result}
+	if err != nil {
+		response.Error = err.Error()
+	}
+	client.send(response)
+}
+
+// Get result of request
+func socketResult(client *socketClient, request socketRequest) (interface{}, error) {
+	switch request.Method {
+	case "list":
+		return socketTasks(), nil
+	case "status":
+		return buildStatus(), nil
+	case "daemons":
+		return runningDaemons(), nil
+	case "subscribe", "unsubscribe":
+		subscribe := request.Method == "subscribe"
+		socketLock.Lock()
+		if client.subscribed != subscribe {
+			client.subscribed = subscribe
+			if subscribe {
+				atomic.AddInt32(&subscribers, 1)
+			} else {
+				atomic.AddInt32(&subscribers, -1)
+			}
+		}
+		socketLock.Unlock()
+		return map[string]bool{"subscribed": request.Method == "subscribe"}, nil
+	}
+	if request.Method != "run" && request.Method != "stop" && request.Method != "restart" {
+		return nil, configError("Method \"" + request.Method + "\" Not Supported")
+	}
+	task := resolveAlias(request.Task)
+	if _, ok := currentMap().Task[task]; !ok {
+		return nil, configError("Task \"" + request.Task + "\" Not Found")
+	}
+	result := map[string]string{"task": task}
+	switch request.Method {
+	case "run":
+		run := newBuildRun("")
+		run.trigger = "socket"
+		if !request.Wait {
+			go func() {
+				handleError(runTaskLimited(run, task))
+			}()
+			return result, nil
+		}
+		err := runTaskLimited(run, task)
+		handleError(err)
+		return result, err
+	case "stop":
+		if !stopDaemons(task, "STOPPED") {
+			return nil, configError("Task \"" + task + "\" Has No Running Daemon")
+		}
+		return result, nil
+	}
+	// Run the task again as daemon
+	stopDaemons(task, "RESTARTING")
+	go func() {
+		handleError(runTask(newBuildRun(""), task, true))
+	}()
+	return result, nil
+}
+
+// Get tasks of config in order of name
+func socketTasks() []socketTask {
+	config := currentMap()
+	names := make([]string, 0, len(config.Task))
+	for name := range config.Task {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tasks := make([]socketTask, 0, len(names))
+	for _, name := range names {
+		define := config.Task[name]
+		tasks = append(tasks, socketTask{name, define.Desc, taskAliases(name), define.Deps})
+	}
+	return tasks
+}
+
+// Check if any client subscribed events
+func hasSubscribers() bool {
+	return atomic.LoadInt32(&subscribers) > 0
+}
+
+// Send event line to subscribed clients
+func broadcastEvent(line []byte) {
+	socketLock.Lock()
+	var clients []*socketClient
+	for client := range socketClients {
+		if client.subscribed {
+			clients = append(clients, client)
+		}
+	}
+	socketLock.Unlock()
+	for _, client := range clients {
+		client.write(line)
+	}
+}
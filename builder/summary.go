@@ -0,0 +1,88 @@
+package builder
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Result of task in build run, status is done, failed, canceled, up to
+// date, skipped or cached
+type taskResult struct {
+	task     string
+	status   string
+	commands int
+	elapsed  time.Duration
+}
+
+// Results of last finished build run and its elapsed time, printed by
+// console on demand
+var lastResults []taskResult
+var lastElapsed time.Duration
+var lastResultsLock sync.Mutex
+
+// Count command run by task, task reference is not counted
+func (run *buildRun) countCommand(task string) {
+	run.resultLock.Lock()
+	run.commands[task]++
+	run.resultLock.Unlock()
+}
+
+// Record result of task with commands counted since last result of it
+func (run *buildRun) result(task string, status string, elapsed time.Duration) {
+	run.resultLock.Lock()
+	run.results = append(run.results, taskResult{task, status, run.commands[task], elapsed})
+	delete(run.commands, task)
+	run.resultLock.Unlock()
+	emitTaskFinished(task, status, elapsed)
+}
+
+// Keep results of finished build run, print them if summary enabled
+func (run *buildRun) finishSummary(elapsed time.Duration) {
+	run.resultLock.Lock()
+	results := append([]taskResult{}, run.results...)
+	run.resultLock.Unlock()
+	lastResultsLock.Lock()
+	lastResults, lastElapsed = results, elapsed
+	lastResultsLock.Unlock()
+	if run.opts.Summary {
+		printSummary(results, elapsed)
+	}
+}
+
+// Print summary of last finished build run
+func printLastSummary() {
+	lastResultsLock.Lock()
+	results, elapsed := lastResults, lastElapsed
+	lastResultsLock.Unlock()
+	if results == nil {
+		log(CLR_W, "No Finished Build Run")
+		return
+	}
+	printSummary(results, elapsed)
+}
+
+// Print table of task results in finish order, with total of commands and
+// failures
+func printSummary(results []taskResult, elapsed time.Duration) {
+	width := len("TASK")
+	for _, result := range results {
+		if len(result.task) > width {
+			width = len(result.task)
+		}
+	}
+	log(CLR_W, "Summary:")
+	log(CLR_W, fmt.Sprintf("    %-*s  %-10s  %8s  %10s", width, "TASK", "STATUS", "COMMANDS", "TIME"))
+	commands, failures := 0, 0
+	for _, result := range results {
+		color := CLR_W
+		if result.status == "failed" {
+			color = CLR_R
+			failures++
+		}
+		commands += result.commands
+		log(color, fmt.Sprintf("    %-*s  %-10s  %8d  %10s", width, result.task, result.status, result.commands, formatElapsed(result.elapsed)))
+	}
+	log(CLR_W, "Tasks "+strconv.Itoa(len(results))+", Commands "+strconv.Itoa(commands)+", Failed "+strconv.Itoa(failures)+", Total "+formatElapsed(elapsed))
+}
@@ -0,0 +1,327 @@
+package builder
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session file of running build.go with daemons, store pid, address and
+// token of local control server used by ps, stop and restart subcommands;
+// only readable by owner
+const sessionFile = ".build/session.json"
+
+// Content of session file, token is required by every request
+type session struct {
+	Pid   int    `json:"pid"`
+	Addr  string `json:"addr"`
+	Token string `json:"token"`
+}
+
+// Status of running daemon command
+type DaemonStatus struct {
+	Task    string    `json:"task"`
+	Pid     int       `json:"pid"`
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+// Command and start time of running daemon commands, locked by process lock
+var daemonStatus = make(map[*exec.Cmd]DaemonStatus)
+
+// Session started once when first daemon start, its token is also required
+// by --serve control server
+var sessionOnce sync.Once
+var sessionStarted bool
+var sessionToken string
+
+// Add endpoints of daemon supervision: GET /daemons list running daemons,
+// POST /stop/{task} and /restart/{task} stop or restart daemons of task
+func handleDaemons(mux *http.ServeMux) {
+	mux.HandleFunc("/daemons", serveDaemons)
+	mux.HandleFunc("/stop/", serveDaemonControl)
+	mux.HandleFunc("/restart/", serveDaemonControl)
+}
+
+// Start local control server and write session file, so daemons could be
+// supervised and tasks forwarded by other build.go process
+func startSession() {
+	sessionOnce.Do(func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			log(CLR_R, "Session "+err.Error())
+			return
+		}
+		secret := make([]byte, 16)
+		if _, err := rand.Read(secret); err != nil {
+			log(CLR_R, "Session "+err.Error())
+			listener.Close()
+			return
+		}
+		token := hex.EncodeToString(secret)
+		content, _ := json.Marshal(session{os.Getpid(), listener.Addr().String(), token})
+		os.MkdirAll(filepath.Dir(sessionFile), 0755)
+		if err := writeSessionFile(content); err != nil {
+			log(CLR_R, "Session "+err.Error())
+			listener.Close()
+			return
+		}
+		processLock.Lock()
+		sessionStarted = true
+		sessionToken = token
+		processLock.Unlock()
+		mux := http.NewServeMux()
+		mux.HandleFunc("/task/", serveTask)
+		handleDaemons(mux)
+		go http.Serve(listener, requireToken(token, rejectCrossOrigin(mux)))
+	})
+}
+
+// Write session file by rename of temp file only readable by owner, so
+// other instance never read partial session or file of stale session
+func writeSessionFile(content []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(sessionFile), "session")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), sessionFile)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+// Reject request without token of session, so other local processes and
+// web pages could not run tasks
+func requireToken(token string, handler http.Handler) http.Handler {
+	expect := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expect) != 1 {
+			serveJSON(w, http.StatusUnauthorized, map[string]string{"error": "Session Token Invalid"})
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Get token of started session, empty if session not started
+func currentSessionToken() string {
+	processLock.Lock()
+	defer processLock.Unlock()
+	return sessionToken
+}
+
+// Remove session file when exit, if it is still of this process
+func removeSession() {
+	processLock.Lock()
+	started := sessionStarted
+	processLock.Unlock()
+	if !started {
+		return
+	}
+	if current, err := readSession(); err == nil && current.Pid == os.Getpid() {
+		os.Remove(sessionFile)
+	}
+}
+
+// Get running daemon commands, in order of task and start time
+func runningDaemons() []DaemonStatus {
+	processLock.Lock()
+	list := make([]DaemonStatus, 0, len(daemonStatus))
+	for _, status := range daemonStatus {
+		list = append(list, status)
+	}
+	processLock.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Task != list[j].Task {
+			return list[i].Task < list[j].Task
+		}
+		return list[i].Started.Before(list[j].Started)
+	})
+	return list
+}
+
+// Response running daemons
+func serveDaemons(w http.ResponseWriter, r *http.Request) {
+	serveJSON(w, http.StatusOK, runningDaemons())
+}
+
+// Stop or restart daemons of task by request
+func serveDaemonControl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		serveJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method Not Allowed"})
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	action, task := parts[0], resolveAlias(parts[1])
+	if _, ok := currentMap().Task[task]; !ok {
+		serveJSON(w, http.StatusNotFound, map[string]string{"error": "Task \"" + task + "\" Not Found"})
+		return
+	}
+	if action == "stop" {
+		if !stopDaemons(task, "STOPPED") {
+			serveJSON(w, http.StatusNotFound, map[string]string{"error": "Task \"" + task + "\" Has No Running Daemon"})
+			return
+		}
+		serveJSON(w, http.StatusOK, map[string]string{"task": task})
+		return
+	}
+	// Run the task again as daemon
+	stopDaemons(task, "RESTARTING")
+	go func() {
+		handleError(runTask(newBuildRun(""), task, true))
+	}()
+	serveJSON(w, http.StatusAccepted, map[string]string{"task": task})
+}
+
+// Read session file of running build.go
+func readSession() (session, error) {
+	var current session
+	content, err := ioutil.ReadFile(sessionFile)
+	if err != nil {
+		return current, errors.New("No Running Session With Daemons")
+	}
+	if err := json.Unmarshal(content, &current); err != nil {
+		return current, errors.New("Session File Invalid: " + err.Error())
+	}
+	return current, nil
+}
+
+// Send request to control server of running session, decode response
+func requestSession(method string, path string, result interface{}) error {
+	return requestSessionTimeout(method, path, result, killGrace*2)
+}
+
+// Send request to control server of running session with timeout, 0 mean
+// no timeout
+func requestSessionTimeout(method string, path string, result interface{}, timeout time.Duration) error {
+	current, err := readSession()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, "http://"+current.Addr+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+current.Token)
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.New("Session Not Running: " + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&failure)
+		return errors.New(failure.Error)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// List running daemons of session in current directory
+func ListDaemons() ([]DaemonStatus, error) {
+	var list []DaemonStatus
+	err := requestSession(http.MethodGet, "/daemons", &list)
+	return list, err
+}
+
+// Stop daemons of task in session of current directory
+func StopDaemon(task string) error {
+	return requestSession(http.MethodPost, "/stop/"+task, nil)
+}
+
+// Restart daemons of task in session of current directory, run the task
+// again after stop them
+func RestartDaemon(task string) error {
+	return requestSession(http.MethodPost, "/restart/"+task, nil)
+}
+
+// Commands killed by shutdown or cancel of run, their exit is expected,
+// locked by process lock
+var killedProcesses = make(map[*exec.Cmd]bool)
+
+// Output line of daemon, with color of log type
+type outputLine struct {
+	color string
+	text  string
+}
+
+// Ring buffer keep recent output lines of daemon
+type outputRing struct {
+	lines []outputLine
+	next  int
+	full  bool
+	lock  sync.Mutex
+}
+
+// Create ring buffer of size lines, nil if replay disabled
+func newOutputRing(size int) *outputRing {
+	if size <= 0 {
+		return nil
+	}
+	return &outputRing{lines: make([]outputLine, size)}
+}
+
+// Keep output line, drop oldest one if full
+func (ring *outputRing) add(color string, text string) {
+	if ring == nil {
+		return
+	}
+	ring.lock.Lock()
+	ring.lines[ring.next] = outputLine{color, text}
+	ring.next = (ring.next + 1) % len(ring.lines)
+	ring.full = ring.full || ring.next == 0
+	ring.lock.Unlock()
+}
+
+// Get kept lines from oldest
+func (ring *outputRing) recent() []outputLine {
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+	if !ring.full {
+		return append([]outputLine{}, ring.lines[:ring.next]...)
+	}
+	return append(append([]outputLine{}, ring.lines[ring.next:]...), ring.lines[:ring.next]...)
+}
+
+// Log daemon exit not caused by stop, restart or shutdown, with its recent
+// output since log may be cleared or scrolled away
+func replayDaemon(task string, index int, ring *outputRing, err error) {
+	reason := "Exited"
+	if err != nil {
+		reason = "Exited: " + err.Error()
+	}
+	if ring == nil {
+		logTask(task, index, "", CLR_R, "Task \""+task+"\" Daemon "+reason)
+		return
+	}
+	lines := ring.recent()
+	logTask(task, index, "", CLR_R, "Task \""+task+"\" Daemon "+reason+", Last "+strconv.Itoa(len(lines))+" Lines of Output:")
+	for _, line := range lines {
+		logTask(task, index, task, line.color, line.text)
+	}
+}
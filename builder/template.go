@@ -0,0 +1,53 @@
+package builder
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Functions could be used in template
+var templateFuncs = template.FuncMap{
+	"join":    func(sep string, items []string) string { return strings.Join(items, sep) },
+	"split":   func(sep string, str string) []string { return strings.Split(str, sep) },
+	"trim":    strings.TrimSpace,
+	"toUpper": strings.ToUpper,
+	"toLower": strings.ToLower,
+	"env":     os.Getenv,
+	"default": func(def string, value string) string {
+		if value == "" {
+			return def
+		}
+		return value
+	},
+}
+
+// Evaluate template write as ${{ }} in string, like ${{ toUpper .NAME }},
+// data is variables and built-in variables of build run
+func (run *buildRun) parseTemplate(str string) (string, error) {
+	if !strings.Contains(str, "${{") {
+		return str, nil
+	}
+	tmpl, err := template.New("command").Delims("${{", "}}").Funcs(templateFuncs).Option("missingkey=zero").Parse(str)
+	if err != nil {
+		return "", configError("Template Invalid: " + err.Error())
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, run.templateData()); err != nil {
+		return "", configError("Template Invalid: " + err.Error())
+	}
+	return buf.String(), nil
+}
+
+// Get data of template, config variable override built-in variable with
+// same name, matrix variable override all
+func (run *buildRun) templateData() map[string]string {
+	data := make(map[string]string)
+	for _, vars := range []map[string]string{builtinVariables, run.vars, currentMap().Variable, run.matrix} {
+		for name, value := range vars {
+			data[name] = value
+		}
+	}
+	return data
+}
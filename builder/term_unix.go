@@ -0,0 +1,16 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package builder
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Check if file is terminal by get its attributes, /dev/null or pipe is not
+func isTerminal(file *os.File) bool {
+	var termios syscall.Termios
+	return ioctl(file.Fd(), ioctlGetTermios, uintptr(unsafe.Pointer(&termios))) == nil
+}
@@ -0,0 +1,13 @@
+package builder
+
+import (
+	"os"
+	"unsafe"
+)
+
+// Check if file is console by get its mode, NUL or pipe is not
+func isTerminal(file *os.File) bool {
+	var mode uint32
+	ret, _, _ := procGetConsoleMode.Call(file.Fd(), uintptr(unsafe.Pointer(&mode)))
+	return ret != 0
+}
@@ -0,0 +1,374 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Lines of log kept for each task and for all tasks in TUI
+const tuiLogLines = 1000
+
+// Interval to redraw TUI if changed
+const tuiRenderInterval = 100 * time.Millisecond
+
+// TUI is showing, log is rendered in panes instead of printed
+var tuiEnabled int32
+
+// Restore terminal mode when TUI stop
+var tuiRestore func()
+
+// Log line in TUI pane
+type tuiLine struct {
+	color string
+	text  string
+}
+
+// Task shown in TUI, status is of last event; daemon mark task had daemon
+// running
+type tuiTask struct {
+	name   string
+	status string
+	daemon bool
+	lines  []tuiLine
+}
+
+// Tasks in order of first run, log of all tasks, selected pane (0 is all
+// tasks) and its scroll offset from bottom, last watch trigger
+var tuiTasks []*tuiTask
+var tuiAll []tuiLine
+var tuiSelected int
+var tuiScroll int
+var tuiTrigger string
+var tuiDirty bool
+var tuiLock sync.Mutex
+
+// Terminal size and running daemons of last render, redraw when changed
+var tuiCols, tuiRows, tuiDaemons int
+
+// Check if TUI is showing
+func tuiActive() bool {
+	return atomic.LoadInt32(&tuiEnabled) == 1
+}
+
+// Show TUI dashboard on alternate screen and read key bindings, stdin and
+// stdout must be terminal
+func startTUI() error {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) || !enableColor(os.Stdout) {
+		return errors.New("TUI Need Terminal of Stdin and Stdout")
+	}
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		return err
+	}
+	tuiRestore = restore
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	atomic.StoreInt32(&tuiEnabled, 1)
+	tuiLock.Lock()
+	tuiDirty = true
+	tuiLock.Unlock()
+	go func() {
+		for range time.Tick(tuiRenderInterval) {
+			if !tuiActive() {
+				return
+			}
+			renderTUI()
+		}
+	}()
+	go readTUIKeys()
+	return nil
+}
+
+// Leave alternate screen and restore terminal mode, before exit
+func stopTUI() {
+	if !atomic.CompareAndSwapInt32(&tuiEnabled, 1, 0) {
+		return
+	}
+	fmt.Print("\x1b[?25h\x1b[?1049l")
+	if tuiRestore != nil {
+		tuiRestore()
+	}
+}
+
+// Add line to log, drop oldest ones if too many
+func appendTUILine(lines []tuiLine, line tuiLine) []tuiLine {
+	lines = append(lines, line)
+	if len(lines) > tuiLogLines {
+		lines = append([]tuiLine{}, lines[len(lines)-tuiLogLines:]...)
+	}
+	return lines
+}
+
+// Get task of TUI by name, add it if first seen; locked by TUI lock
+func tuiTaskOf(name string) *tuiTask {
+	for _, task := range tuiTasks {
+		if task.name == name {
+			return task
+		}
+	}
+	task := &tuiTask{name: name}
+	tuiTasks = append(tuiTasks, task)
+	return task
+}
+
+// Update panes by event, same as --porcelain event
+func tuiEvent(record map[string]interface{}) {
+	event, _ := record["event"].(string)
+	name, _ := record["task"].(string)
+	tuiLock.Lock()
+	defer tuiLock.Unlock()
+	tuiDirty = true
+	switch event {
+	case "task_started":
+		tuiTaskOf(name).status = "running"
+	case "task_finished":
+		status, _ := record["status"].(string)
+		tuiTaskOf(name).status = status
+	case "watch_triggered":
+		file, _ := record["file"].(string)
+		tuiTrigger = file + " -> " + name + " at " + time.Now().Format("15:04:05")
+	case "command_output", "log":
+		text, _ := record["line"].(string)
+		color := CLR_W
+		if event == "log" {
+			text, _ = record["message"].(string)
+			color = tuiLevelColor(record["level"])
+		} else if record["stream"] == "stderr" {
+			color = CLR_R
+		}
+		line := tuiLine{color, text}
+		if name != "" {
+			task := tuiTaskOf(name)
+			task.lines = appendTUILine(task.lines, line)
+			line.text = "[" + name + "] " + text
+		}
+		tuiAll = appendTUILine(tuiAll, line)
+	}
+}
+
+// Get color of log level in event
+func tuiLevelColor(level interface{}) string {
+	switch level {
+	case "err":
+		return CLR_R
+	case "run":
+		return CLR_G
+	case "cmd":
+		return CLR_B
+	}
+	return CLR_W
+}
+
+// Get color of task status
+func tuiStatusColor(status string) string {
+	switch status {
+	case "running":
+		return CLR_Y
+	case "done", "up to date", "cached":
+		return CLR_G
+	case "failed":
+		return CLR_R
+	}
+	if strings.HasPrefix(status, "daemon") {
+		return CLR_B
+	}
+	return CLR_W
+}
+
+// Cut text to width of terminal columns
+func tuiFit(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	text = strings.Replace(text, "\t", "    ", -1)
+	runes := []rune(text)
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return text + strings.Repeat(" ", width-len(runes))
+}
+
+// Redraw TUI if changed: header with watch status and last trigger, task
+// list with status on left, log of selected task on right, and keys
+func renderTUI() {
+	running := runningDaemons()
+	daemonCount := make(map[string]int)
+	for _, status := range running {
+		daemonCount[status.Task]++
+	}
+	cols, rows := terminalSize(os.Stdout)
+	tuiLock.Lock()
+	defer tuiLock.Unlock()
+	if !tuiDirty && cols == tuiCols && rows == tuiRows && len(running) == tuiDaemons {
+		return
+	}
+	tuiDirty, tuiCols, tuiRows, tuiDaemons = false, cols, rows, len(running)
+	if rows < 5 || cols < 20 {
+		return
+	}
+	var screen strings.Builder
+	screen.WriteString("\x1b[H")
+	header := " build.go  Watching " + strconv.Itoa(len(currentMap().Watch)) + " Patterns"
+	if tuiTrigger != "" {
+		header += "  Last Trigger " + tuiTrigger
+	}
+	screen.WriteString("\x1b[7m" + tuiFit(header, cols) + "\x1b[0m\r\n")
+	// Task list pane, first entry is log of all tasks
+	listWidth := len("All Tasks")
+	for _, task := range tuiTasks {
+		if len(task.name) > listWidth {
+			listWidth = len(task.name)
+		}
+	}
+	listWidth += 14
+	if listWidth > cols/3 {
+		listWidth = cols / 3
+	}
+	if tuiSelected > len(tuiTasks) {
+		tuiSelected = len(tuiTasks)
+	}
+	var entries []string
+	var colors []string
+	entries = append(entries, "All Tasks")
+	colors = append(colors, CLR_W)
+	for _, task := range tuiTasks {
+		status := task.status
+		if count := daemonCount[task.name]; count > 0 {
+			task.daemon = true
+			status = "daemon " + strconv.Itoa(count)
+		} else if task.daemon && status == "running" {
+			status = "stopped"
+		}
+		entries = append(entries, fmt.Sprintf("%-*s %s", listWidth-14, task.name, status))
+		colors = append(colors, tuiStatusColor(status))
+	}
+	lines := tuiAll
+	if tuiSelected > 0 {
+		lines = tuiTasks[tuiSelected-1].lines
+	}
+	height := rows - 2
+	if max := len(lines) - height; tuiScroll > max {
+		tuiScroll = max
+	}
+	if tuiScroll < 0 {
+		tuiScroll = 0
+	}
+	end := len(lines) - tuiScroll
+	start := end - height
+	if start < 0 {
+		start = 0
+	}
+	visible := lines[start:end]
+	logWidth := cols - listWidth - 3
+	for row := 0; row < height; row++ {
+		if row < len(entries) {
+			entry := tuiFit(" "+entries[row], listWidth)
+			if row == tuiSelected {
+				screen.WriteString("\x1b[7m" + entry + "\x1b[0m")
+			} else {
+				screen.WriteString(colors[row] + entry + "\x1b[0m")
+			}
+		} else {
+			screen.WriteString(strings.Repeat(" ", listWidth))
+		}
+		screen.WriteString(" │ ")
+		if row < len(visible) {
+			screen.WriteString(visible[row].color + tuiFit(visible[row].text, logWidth) + "\x1b[0m")
+		} else {
+			screen.WriteString(strings.Repeat(" ", logWidth))
+		}
+		screen.WriteString("\r\n")
+	}
+	footer := " ↑/↓ select  PgUp/PgDn scroll  r rerun  x stop daemons  q quit"
+	if tuiScroll > 0 {
+		footer += "  (scrolled " + strconv.Itoa(tuiScroll) + " lines)"
+	}
+	screen.WriteString("\x1b[7m" + tuiFit(footer, cols) + "\x1b[0m")
+	os.Stdout.WriteString(screen.String())
+}
+
+// Read keys of TUI, a read could get more than one key
+func readTUIKeys() {
+	buf := make([]byte, 64)
+	for tuiActive() {
+		count, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, key := range splitKeys(string(buf[:count])) {
+			handleTUIKey(key)
+		}
+	}
+}
+
+// Split input to keys, escape sequence like arrow key is one key
+func splitKeys(input string) []string {
+	var keys []string
+	for len(input) > 0 {
+		size := 1
+		if strings.HasPrefix(input, "\x1b[") {
+			size = 2
+			for size < len(input) {
+				size++
+				if char := input[size-1]; char == '~' || char >= 'A' && char <= 'Z' || char >= 'a' && char <= 'z' {
+					break
+				}
+			}
+		}
+		keys = append(keys, input[:size])
+		input = input[size:]
+	}
+	return keys
+}
+
+// Handle key of TUI: arrows or j/k select pane, page up/down or u/d scroll
+// log, r rerun selected task or last tasks, x stop daemons of selected
+// task, q quit
+func handleTUIKey(key string) {
+	tuiLock.Lock()
+	task := ""
+	if tuiSelected > 0 && tuiSelected <= len(tuiTasks) {
+		task = tuiTasks[tuiSelected-1].name
+	}
+	_, rows := terminalSize(os.Stdout)
+	page := rows / 2
+	switch key {
+	case "\x1b[A", "k":
+		if tuiSelected > 0 {
+			tuiSelected--
+		}
+		tuiScroll = 0
+	case "\x1b[B", "j":
+		if tuiSelected < len(tuiTasks) {
+			tuiSelected++
+		}
+		tuiScroll = 0
+	case "\x1b[5~", "u":
+		tuiScroll += page
+	case "\x1b[6~", "d":
+		tuiScroll -= page
+	}
+	tuiDirty = true
+	tuiLock.Unlock()
+	switch key {
+	case "r":
+		if task != "" {
+			rerunTasks([]string{task})
+		} else {
+			rerunTasks(nil)
+		}
+	case "x":
+		if task != "" && !stopDaemons(task, "STOPPED") {
+			logTask(task, -1, "", CLR_R, "Task \""+task+"\" Has No Running Daemon")
+		}
+	case "q":
+		stopTUI()
+		log(CLR_G, "Shutting Down By Console")
+		shutdown(0)
+	}
+}
@@ -0,0 +1,9 @@
+package builder
+
+import (
+	"syscall"
+)
+
+// Ioctl requests to get and set terminal attributes
+const ioctlGetTermios = syscall.TCGETS
+const ioctlSetTermios = syscall.TCSETS
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package builder
+
+import (
+	"errors"
+	"os"
+)
+
+// TUI is only supported on linux and darwin
+func makeRaw(file *os.File) (func(), error) {
+	return nil, errors.New("TUI Not Supported on This Platform")
+}
+
+// Terminal size is not read on this platform
+func terminalSize(file *os.File) (int, int) {
+	return 80, 24
+}
@@ -0,0 +1,40 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package builder
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Put terminal in raw mode to read key by key without echo, signal keys
+// like Ctrl-C still work; return function to restore it
+func makeRaw(file *os.File) (func(), error) {
+	var saved syscall.Termios
+	if err := ioctl(file.Fd(), ioctlGetTermios, uintptr(unsafe.Pointer(&saved))); err != nil {
+		return nil, err
+	}
+	raw := saved
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO | syscall.IEXTEN
+	raw.Iflag &^= syscall.IXON | syscall.ICRNL
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(file.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(&raw))); err != nil {
+		return nil, err
+	}
+	return func() {
+		ioctl(file.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(&saved)))
+	}, nil
+}
+
+// Get columns and rows of terminal, 80x24 if unknown
+func terminalSize(file *os.File) (int, int) {
+	size := winsize{rows: 24, cols: 80}
+	ioctl(file.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&size)))
+	if size.cols == 0 || size.rows == 0 {
+		return 80, 24
+	}
+	return int(size.cols), int(size.rows)
+}
@@ -0,0 +1,357 @@
+package builder
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Check config file without run any command, with env file and variables
+// of options; return problems found
+func Validate(path string) []string {
+	define, err := ReadConfig(path)
+	if err != nil {
+		return []string{"Config " + err.Error()}
+	}
+	if define.Variable == nil {
+		define.Variable = make(map[string]string)
+	}
+	if _, ok := define.Variable["ARGS"]; !ok {
+		define.Variable["ARGS"] = ""
+	}
+	for _, pair := range varOverrides {
+		if idx := strings.Index(pair, "="); idx > 0 {
+			define.Variable[pair[:idx]] = pair[idx+1:]
+		}
+	}
+	var problems []string
+	if envFile != "" {
+		if err := loadEnvFile(expandPath(envFile), &define); err != nil {
+			problems = append(problems, err.Error())
+		}
+	} else if _, err := os.Stat(".env"); err == nil {
+		if err := loadEnvFile(".env", &define); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return append(problems, define.validateConfig()...)
+}
+
+// Check build map, return problems found
+func (config BuildMap) validateConfig() []string {
+	var problems []string
+	// Variables reference undefined variable or circular reference
+	names := make([]string, 0, len(config.Variable))
+	for name := range config.Variable {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		problems = append(problems, config.checkRefs("Variable \""+name+"\"", config.Variable[name])...)
+	}
+	visited := make(map[string]bool)
+	for _, name := range names {
+		if chain := findCycle(name, nil, visited, config.variableRefs); chain != "" {
+			problems = append(problems, "Variable Circular Reference: "+chain)
+		}
+	}
+	// Required variables not set, checked without ask like validate is
+	// non-interactive
+	if missing := missingVars(nil, config.RequiredVars, config.lookupAnswer); len(missing) > 0 {
+		problems = append(problems, "Required Variables Not Set: "+strings.Join(missing, ", "))
+	}
+	problems = append(problems, config.checkAliases()...)
+	// Tasks reference undefined task or variable, or circular reference
+	tasks := make([]string, 0, len(config.Task))
+	for name := range config.Task {
+		tasks = append(tasks, name)
+	}
+	sort.Strings(tasks)
+	for _, task := range tasks {
+		problems = append(problems, config.validateTask(task, config.Task[task])...)
+	}
+	visited = make(map[string]bool)
+	for _, task := range tasks {
+		if chain := findCycle(task, nil, visited, config.existTaskRefs); chain != "" {
+			problems = append(problems, "Task Circular Reference: "+chain)
+		}
+	}
+	// Watches reference undefined task, or pattern match no file
+	patterns := make([]string, 0, len(config.Watch))
+	for pattern := range config.Watch {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		watch := config.Watch[pattern]
+		where := "Watch \"" + pattern + "\""
+		if _, err := watch.ops(); err != nil {
+			problems = append(problems, where+" "+err.Error())
+		}
+		if len(watch.Task) == 0 {
+			problems = append(problems, where+" Has No Task")
+		}
+		if watch.Policy != "" && !containsString(watchPolicies, watch.Policy) {
+			problems = append(problems, where+" Policy \""+watch.Policy+"\" Not Supported")
+		}
+		for _, ref := range watch.Task {
+			if task := taskRef(ref); task == "" {
+				problems = append(problems, where+" Task Reference \""+ref+"\" Invalid")
+			} else if _, ok := config.Task[config.resolveAlias(task)]; !ok {
+				problems = append(problems, where+" Task \""+task+"\" Not Found")
+			}
+		}
+		refProblems := config.checkRefs(where, pattern)
+		problems = append(problems, refProblems...)
+		// Pattern use variable of command output could not be checked
+		path, err := config.expandVariables(pattern)
+		if err != nil && len(refProblems) == 0 {
+			problems = append(problems, where+" "+err.Error())
+		}
+		if err != nil || len(refProblems) > 0 || strings.Contains(path, "${") || strings.Contains(path, "$(") {
+			continue
+		}
+		if matches, err := globPath(expandPath(path)); err != nil {
+			problems = append(problems, where+" "+err.Error())
+		} else if len(matches) == 0 {
+			problems = append(problems, where+" Match No File")
+		}
+	}
+	// Schedules with invalid expression or undefined task
+	exprs := make([]string, 0, len(config.Schedule))
+	for expr := range config.Schedule {
+		exprs = append(exprs, expr)
+	}
+	sort.Strings(exprs)
+	for _, expr := range exprs {
+		if _, err := parseCron(expr); err != nil {
+			problems = append(problems, err.Error())
+		}
+		if task := taskRef(config.Schedule[expr]); task == "" {
+			problems = append(problems, "Schedule \""+expr+"\" Task Reference Invalid")
+		} else if _, ok := config.Task[config.resolveAlias(task)]; !ok {
+			problems = append(problems, "Schedule \""+expr+"\" Task \""+task+"\" Not Found")
+		}
+	}
+	return problems
+}
+
+// Check task references and variables in task define
+func (config BuildMap) validateTask(task string, define Task) []string {
+	var problems []string
+	where := "Task \"" + task + "\""
+	// Matrix variables are defined by task itself
+	checkRefs := func(where string, str string) []string {
+		for _, ref := range varRegex.FindAllString(str, -1) {
+			if name, _, _ := splitDefault(extractRef(ref)); define.Matrix[name] != nil {
+				str = strings.Replace(str, ref, "", -1)
+			}
+		}
+		return config.checkRefs(where, str)
+	}
+	for _, ref := range config.taskRefs(define) {
+		if _, ok := config.Task[ref]; !ok {
+			problems = append(problems, where+" Reference Task \""+ref+"\" Not Found")
+		}
+	}
+	if err := checkShell(define.Shell); err != nil {
+		problems = append(problems, where+" "+err.Error())
+	}
+	if define.Log != "" && !containsString(logLevels, define.Log) {
+		problems = append(problems, where+" Log \""+define.Log+"\" Not Supported")
+	}
+	for _, matcher := range define.Problems {
+		if _, _, err := matcher.compile(); err != nil {
+			problems = append(problems, where+" "+err.Error())
+		}
+	}
+	if define.Cache && len(define.Generates) == 0 {
+		problems = append(problems, where+" Cache Without Generates")
+	}
+	for _, name := range define.Platforms {
+		if !containsString(platforms, strings.ToLower(name)) {
+			problems = append(problems, where+" Platform \""+name+"\" Not Supported")
+		}
+	}
+	problems = append(problems, checkRefs(where+" When", define.When)...)
+	if define.Requires != nil {
+		for _, str := range append(append([]string{}, define.Requires.Commands...), define.Requires.Files...) {
+			problems = append(problems, checkRefs(where+" Requires \""+str+"\"", str)...)
+		}
+	}
+	problems = append(problems, checkRefs(where+" Dir", define.Dir)...)
+	for _, name := range sortedKeys(define.Env) {
+		problems = append(problems, checkRefs(where+" Env \""+name+"\"", define.Env[name])...)
+	}
+	var checkCommands func(cmds []Command)
+	checkCommands = func(cmds []Command) {
+		for _, cmd := range cmds {
+			checkCommands(cmd.Parallel)
+			if commandRef(cmd.Cmd) == "" {
+				problems = append(problems, checkRefs(where+" Command \""+cmd.Cmd+"\"", cmd.Cmd)...)
+			}
+			for _, stage := range append(append([]string{}, cmd.Pipe...), cmd.Output) {
+				problems = append(problems, checkRefs(where+" Pipe \""+stage+"\"", stage)...)
+			}
+			for _, name := range sortedKeys(cmd.Env) {
+				problems = append(problems, checkRefs(where+" Env \""+name+"\"", cmd.Env[name])...)
+			}
+		}
+	}
+	checkCommands(define.Cmds)
+	var matrixNames []string
+	for name := range define.Matrix {
+		matrixNames = append(matrixNames, name)
+	}
+	sort.Strings(matrixNames)
+	for _, name := range matrixNames {
+		if len(define.Matrix[name]) == 0 {
+			problems = append(problems, where+" Matrix Variable \""+name+"\" Has No Value")
+		}
+		for _, value := range define.Matrix[name] {
+			problems = append(problems, checkRefs(where+" Matrix Variable \""+name+"\"", value)...)
+		}
+	}
+	return problems
+}
+
+// Check ${} references and template in string, where is shown in problem
+func (config BuildMap) checkRefs(where string, str string) []string {
+	var problems []string
+	for _, ref := range varRegex.FindAllString(str, -1) {
+		name, _, hasDefault := splitDefault(extractRef(ref))
+		if hasDefault {
+			continue
+		}
+		if strings.HasPrefix(name, "env:") {
+			if _, ok := os.LookupEnv(name[len("env:"):]); !ok {
+				problems = append(problems, where+" Environment Variable \""+name[len("env:"):]+"\" Not Found")
+			}
+			continue
+		}
+		if _, declared := config.Prompt[name]; declared {
+			continue
+		}
+		if _, ok := config.lookupVariable(name); !ok && !isRunVariable(name) {
+			problems = append(problems, where+" Variable \""+name+"\" Not Found")
+		}
+	}
+	if strings.Contains(str, "${{") {
+		if _, err := template.New("command").Delims("${{", "}}").Funcs(templateFuncs).Parse(str); err != nil {
+			problems = append(problems, where+" Template Invalid: "+err.Error())
+		}
+	}
+	return problems
+}
+
+// Find circular reference from name, return reference chain or empty
+func findCycle(name string, stack []string, visited map[string]bool, refs func(string) []string) string {
+	for idx, ref := range stack {
+		if ref == name {
+			return strings.Join(append(stack[idx:], name), " -> ")
+		}
+	}
+	if visited[name] {
+		return ""
+	}
+	visited[name] = true
+	stack = append(stack, name)
+	for _, ref := range refs(name) {
+		if chain := findCycle(ref, stack, visited, refs); chain != "" {
+			return chain
+		}
+	}
+	return ""
+}
+
+// Get defined variables referenced by variable
+func (config BuildMap) variableRefs(name string) []string {
+	var refs []string
+	for _, ref := range varRegex.FindAllString(config.Variable[name], -1) {
+		refName, _, _ := splitDefault(extractRef(ref))
+		if _, ok := config.Variable[refName]; ok {
+			refs = append(refs, refName)
+		}
+	}
+	return refs
+}
+
+// Get defined tasks referenced by task
+func (config BuildMap) existTaskRefs(task string) []string {
+	var refs []string
+	for _, ref := range config.taskRefs(config.Task[task]) {
+		if _, ok := config.Task[ref]; ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// Get tasks referenced by task: deps, hooks, and commands reference task;
+// aliases are resolved by the config
+func (config BuildMap) taskRefs(define Task) []string {
+	var refs []string
+	for _, dep := range define.Deps {
+		if ref := extractRef(dep); ref != "" {
+			dep = ref
+		}
+		refs = append(refs, config.resolveAlias(dep))
+	}
+	for _, hook := range []string{define.OnSuccess, define.OnFailure} {
+		if ref := taskRef(hook); ref != "" {
+			refs = append(refs, config.resolveAlias(ref))
+		}
+	}
+	var walk func(cmds []Command)
+	walk = func(cmds []Command) {
+		for _, cmd := range cmds {
+			walk(cmd.Parallel)
+			if ref := commandRef(cmd.Cmd); ref != "" {
+				refs = append(refs, config.resolveAlias(ref))
+			}
+		}
+	}
+	walk(define.Cmds)
+	return refs
+}
+
+// Get task name of task reference like ${task} or ${#task}, or plain name
+func taskRef(str string) string {
+	if ref := extractRef(str); ref != "" {
+		str = ref
+	}
+	return strings.TrimPrefix(str, "#")
+}
+
+// Get task name if command is task reference, or empty
+func commandRef(command string) string {
+	ref := extractRef(strings.TrimPrefix(command, "-"))
+	if ref == "" || strings.HasPrefix(ref, "env:") {
+		return ""
+	}
+	return strings.TrimPrefix(ref, "#")
+}
+
+// Expand nested variables in string, without run command of variable or
+// ask variable; error if variable not set
+func (config BuildMap) expandVariables(str string) (string, error) {
+	for depth := 0; depth < 10; depth++ {
+		expanded, err := config.replaceVariables(str, config.lookupAnswer)
+		if err != nil || expanded == str {
+			return str, err
+		}
+		str = expanded
+	}
+	return str, nil
+}
+
+// Get sorted keys of map
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,125 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Webhook define in notify section, receive JSON payload of build result
+type Webhook struct {
+	URL string
+	// Results to notify, in success and failure, default is failure
+	On []string
+	// Count of last log lines in payload, default is 20
+	Lines int
+}
+
+// Max log lines kept for webhook payload
+const logTailSize = 100
+
+// Last log lines without color
+var logTail []string
+var logTailLock sync.Mutex
+
+// Payload posted to webhook, text is summary for chat like Slack
+type webhookPayload struct {
+	Task     string   `json:"task"`
+	Status   string   `json:"status"`
+	Duration string   `json:"duration"`
+	Error    string   `json:"error,omitempty"`
+	Lines    []string `json:"lines"`
+	Text     string   `json:"text"`
+}
+
+// Keep log line for webhook payload
+func tailLog(line string) {
+	logTailLock.Lock()
+	logTail = append(logTail, line)
+	if len(logTail) > logTailSize {
+		logTail = logTail[len(logTail)-logTailSize:]
+	}
+	logTailLock.Unlock()
+}
+
+// Get last n log lines
+func lastLogLines(n int) []string {
+	logTailLock.Lock()
+	defer logTailLock.Unlock()
+	if n > len(logTail) {
+		n = len(logTail)
+	}
+	return append([]string{}, logTail[len(logTail)-n:]...)
+}
+
+// Post build result to webhooks in notify section, wait until all sent
+func notifyWebhooks(tasks []string, err error, elapsed time.Duration) {
+	config := currentMap()
+	if len(config.Notify) == 0 {
+		return
+	}
+	task := strings.Join(tasks, " ")
+	status := "success"
+	text := "Task \"" + task + "\" Succeeded in " + formatElapsed(elapsed)
+	var errText string
+	if err != nil {
+		status = "failure"
+		errText = err.Error()
+		text = "Task \"" + task + "\" Failed in " + formatElapsed(elapsed) + ": " + errText
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	var group sync.WaitGroup
+	for _, hook := range config.Notify {
+		on := hook.On
+		if len(on) == 0 {
+			on = []string{"failure"}
+		}
+		if !containsString(on, status) {
+			continue
+		}
+		url, parseErr := parseVariable(hook.URL)
+		if parseErr != nil {
+			log(CLR_R, "Notify "+parseErr.Error())
+			continue
+		}
+		lines := hook.Lines
+		if lines <= 0 {
+			lines = 20
+		}
+		payload, _ := json.Marshal(webhookPayload{
+			Task:     task,
+			Status:   status,
+			Duration: formatElapsed(elapsed),
+			Error:    errText,
+			Lines:    lastLogLines(lines),
+			Text:     text,
+		})
+		group.Add(1)
+		go func(url string) {
+			defer group.Done()
+			resp, err := client.Post(url, "application/json", bytes.NewReader(payload))
+			if err != nil {
+				log(CLR_R, "Notify "+err.Error())
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				log(CLR_R, "Notify "+url+" Response "+resp.Status)
+			}
+		}(url)
+	}
+	group.Wait()
+}
+
+// Check if string in list
+func containsString(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"github.com/codegangsta/cli"
+	"github.com/imeoer/build.go/builder"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Subcommand print shell completion script, which complete task names by
+// config in current directory
+var completionCommand = cli.Command{
+	Name:  "completion",
+	Usage: "Print completion script of bash, zsh or fish; tasks print task names and aliases, tasks zsh print them for _describe",
+	Action: func(c *cli.Context) {
+		prog := filepath.Base(os.Args[0])
+		// Shell function name could only be identifier
+		fn := "_" + regexp.MustCompile("[^A-Za-z0-9_]").ReplaceAllString(prog, "_") + "_complete"
+		switch shell := c.Args().First(); shell {
+		case "bash":
+			fmt.Printf(`%[2]s() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    [[ "$cur" == -* ]] && return
+    COMPREPLY=($(compgen -W "$(%[1]s completion tasks 2>/dev/null)" -- "$cur"))
+}
+complete -F %[2]s %[1]s
+`, prog, fn)
+		case "zsh":
+			fmt.Printf(`#compdef %[1]s
+%[2]s() {
+    local -a tasks
+    tasks=(${(f)"$(%[1]s completion tasks zsh 2>/dev/null)"})
+    _describe 'task' tasks
+}
+compdef %[2]s %[1]s
+`, prog, fn)
+		case "fish":
+			fmt.Printf("complete -c %[1]s -f -a '(%[1]s completion tasks 2>/dev/null)'\n", prog)
+		case "tasks":
+			define, err := builder.ReadConfig(c.GlobalString("config"))
+			if err != nil {
+				os.Exit(1)
+			}
+			// Each line of zsh is name:description, colon in name escaped
+			zsh := c.Args().Get(1) == "zsh"
+			names := make([]string, 0, len(define.Task))
+			for name, task := range define.Task {
+				names = append(names, completionName(name, task.Desc, zsh))
+				for _, alias := range task.Aliases {
+					names = append(names, completionName(alias, "alias of "+name, zsh))
+				}
+			}
+			sort.Strings(names)
+			fmt.Println(strings.Join(names, "\n"))
+		default:
+			builder.Log(builder.CLR_R, "Shell \""+shell+"\" Not Supported, Expect bash, zsh or fish")
+			os.Exit(1)
+		}
+	},
+}
+
+// Format name of task or alias for completion, with description for zsh
+func completionName(name string, desc string, zsh bool) string {
+	if !zsh {
+		return name
+	}
+	name = strings.Replace(name, ":", "\\:", -1)
+	if desc != "" {
+		name += ":" + strings.Replace(desc, "\n", " ", -1)
+	}
+	return name
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"github.com/codegangsta/cli"
+	"github.com/imeoer/build.go/builder"
+	"os"
+	"time"
+)
+
+// Subcommand list running daemon commands of build.go in current directory
+var psCommand = cli.Command{
+	Name:  "ps",
+	Usage: "List running daemon commands of build.go session of project in current or parent directory",
+	Action: func(c *cli.Context) {
+		builder.EnterProjectDir(c.GlobalString("config"))
+		list, err := builder.ListDaemons()
+		if err != nil {
+			builder.Log(builder.CLR_R, err.Error())
+			os.Exit(1)
+		}
+		if len(list) == 0 {
+			fmt.Println("No Running Daemon")
+			return
+		}
+		fmt.Printf("%-16s  %-7s  %-9s  %s\n", "TASK", "PID", "UPTIME", "COMMAND")
+		for _, status := range list {
+			uptime := time.Since(status.Started).Round(time.Second)
+			fmt.Printf("%-16s  %-7d  %-9s  %s\n", status.Task, status.Pid, uptime, status.Command)
+		}
+	},
+}
+
+// Subcommand stop daemon commands of task
+var stopCommand = cli.Command{
+	Name:  "stop",
+	Usage: "Stop daemon commands of task in build.go session of project in current or parent directory",
+	Action: func(c *cli.Context) {
+		controlDaemon(c, "Stopped", builder.StopDaemon)
+	},
+}
+
+// Subcommand stop daemon commands of task, then run the task again
+var restartCommand = cli.Command{
+	Name:  "restart",
+	Usage: "Restart daemon commands of task in build.go session of project in current or parent directory",
+	Action: func(c *cli.Context) {
+		controlDaemon(c, "Restarting", builder.RestartDaemon)
+	},
+}
+
+// Apply control to each task in arguments
+func controlDaemon(c *cli.Context, state string, control func(string) error) {
+	builder.EnterProjectDir(c.GlobalString("config"))
+	if len(c.Args()) == 0 {
+		builder.Log(builder.CLR_R, "Task Name Required")
+		os.Exit(1)
+	}
+	for _, task := range c.Args() {
+		if err := control(task); err != nil {
+			builder.Log(builder.CLR_R, err.Error())
+			os.Exit(1)
+		}
+		builder.Log(builder.CLR_W, "Task \""+task+"\" "+state)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"github.com/codegangsta/cli"
+	"github.com/imeoer/build.go/builder"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Subcommand run ad-hoc command with variables, environment and working
+// directory of config or task
+var execCommand = cli.Command{
+	Name:  "exec",
+	Usage: "Run command with variables and environment of config, like exec \"go test ${PKG}\"",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "task, t",
+			Usage: "Use working directory, environment and options of task",
+		},
+	},
+	Action: func(c *cli.Context) {
+		if len(c.Args()) == 0 {
+			builder.Log(builder.CLR_R, "Command Required")
+			os.Exit(1)
+		}
+		err := builder.Configure(builder.Options{
+			EnvFile:        c.GlobalString("env-file"),
+			Vars:           c.GlobalStringSlice("var"),
+			NoUserConfig:   c.GlobalBool("no-user-config"),
+			NoColor:        c.GlobalBool("no-color"),
+			DryRun:         c.GlobalBool("dry-run"),
+			Verbose:        c.GlobalBool("verbose"),
+			Pty:            c.GlobalBool("pty"),
+			Timeout:        c.GlobalDuration("timeout"),
+			NonInteractive: c.GlobalBool("non-interactive"),
+		})
+		if err != nil {
+			builder.Log(builder.CLR_R, err.Error())
+			os.Exit(1)
+		}
+		if _, err := builder.LoadConfig(c.GlobalString("config")); err != nil {
+			builder.Log(builder.CLR_R, err.Error())
+			os.Exit(1)
+		}
+		runner := &builder.Runner{}
+		err = runner.Exec(context.Background(), strings.Join(c.Args(), " "), c.String("task"))
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+			os.Exit(exitErr.ExitCode())
+		}
+		if err != nil {
+			builder.Log(builder.CLR_R, err.Error())
+			os.Exit(1)
+		}
+	},
+}
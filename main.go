@@ -0,0 +1,455 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/codegangsta/cli"
+	"github.com/imeoer/build.go/builder"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	// Init cli app
+	app := cli.NewApp()
+	app.Name = "Build.go"
+	app.Usage = "A Simple Automation Task Build Tool"
+	app.Author = "https://github.com/imeoer"
+	app.Email = "imeoer@gmail.com"
+	app.Version = "0.1.0"
+	app.Commands = []cli.Command{completionCommand, validateCommand, initCommand, execCommand, psCommand, stopCommand, restartCommand, reportCommand}
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "config, c",
+			Value: "build.yml",
+			Usage: "Build.go YAML, JSON or TOML Format Config File",
+		},
+		cli.StringFlag{
+			Name:  "env-file",
+			Usage: "Load variables and command environment from file, default .env",
+		},
+		cli.BoolFlag{
+			Name:  "no-user-config",
+			Usage: "Not merge variables and tasks of user config ~/.config/build.go/build.yml",
+		},
+		cli.StringSliceFlag{
+			Name:  "var",
+			Value: &cli.StringSlice{},
+			Usage: "Override variable as KEY=VALUE, could repeat",
+		},
+		cli.BoolFlag{
+			Name:  "list, l",
+			Usage: "List all tasks and watched files",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run, n",
+			Usage: "Print expanded commands in order without execute",
+		},
+		cli.BoolFlag{
+			Name:  "once",
+			Usage: "Run tasks and exit, even if config has watch, schedule or server",
+		},
+		cli.BoolFlag{
+			Name:  "watch-only",
+			Usage: "Start to watch without run tasks first",
+		},
+		cli.StringFlag{
+			Name:  "instance",
+			Usage: "If build.go already running in directory: error, wait, forward tasks to it, or allow; default error if watching",
+		},
+		cli.BoolFlag{
+			Name:  "verbose, V",
+			Usage: "Print expanded command line with shell before execute, like set -x",
+		},
+		cli.BoolFlag{
+			Name:  "yes, y",
+			Usage: "Answer yes to confirm of tasks, for scripts and CI",
+		},
+		cli.BoolFlag{
+			Name:  "non-interactive",
+			Usage: "Fail on variable without value instead of ask for it",
+		},
+		cli.BoolFlag{
+			Name:  "pty",
+			Usage: "Run commands under pseudo terminal to keep their colors and progress, linux and darwin only",
+		},
+		cli.StringFlag{
+			Name:  "log-format",
+			Value: "text",
+			Usage: "Log format, text or json",
+		},
+		cli.BoolFlag{
+			Name:  "porcelain",
+			Usage: "Write newline delimited JSON events to stdout instead of log, for editor integration",
+		},
+		cli.StringFlag{
+			Name:  "log-file",
+			Usage: "Also write all output to file",
+		},
+		cli.IntFlag{
+			Name:  "log-max-size",
+			Usage: "Rotate log file when exceed size in MB, keep 3 backups",
+		},
+		cli.BoolFlag{
+			Name:  "silent, s",
+			Usage: "Hide detail log when running build",
+		},
+		cli.BoolFlag{
+			Name:  "no-color",
+			Usage: "Print log without color, also disabled by NO_COLOR or non terminal output",
+		},
+		cli.BoolFlag{
+			Name:  "timestamps",
+			Usage: "Prefix log lines with time",
+		},
+		cli.IntFlag{
+			Name:  "max-line-length",
+			Usage: "Truncate output line longer than bytes, 0 mean no limit",
+		},
+		cli.IntFlag{
+			Name:  "max-line-rate",
+			Usage: "Suppress output lines of each command exceed count per second, 0 mean no limit",
+		},
+		cli.BoolFlag{
+			Name:  "profile",
+			Usage: "Print elapsed time of tasks and commands, slowest first, after build",
+		},
+		cli.BoolFlag{
+			Name:  "summary",
+			Usage: "Print summary of tasks, commands, failures and time after build, s in console print it on demand",
+		},
+		cli.BoolFlag{
+			Name:  "record",
+			Usage: "Append tasks, commands, time and exit code of each build run to .build/history.jsonl for report",
+		},
+		cli.BoolFlag{
+			Name:  "keep, k",
+			Usage: "Keep log when watched file change again",
+		},
+		cli.StringFlag{
+			Name:  "clear",
+			Usage: "Clear log before watch triggered run: never, on-change, or on-error only if last run failed; default on-change, or never if --keep",
+		},
+		cli.IntFlag{
+			Name:  "daemon-buffer",
+			Value: 50,
+			Usage: "Lines of daemon output replayed when daemon exit unexpectedly, 0 to disable",
+		},
+		cli.DurationFlag{
+			Name:  "max-duration",
+			Usage: "Max duration of a build run, kill commands of the run when exceed, exit if not watching",
+		},
+		cli.DurationFlag{
+			Name:  "debounce",
+			Usage: "Coalesce changes of same watch pattern in window, like 300ms",
+		},
+		cli.BoolFlag{
+			Name:  "poll",
+			Usage: "Scan watched files on interval instead of file system events, for NFS and docker volumes",
+		},
+		cli.DurationFlag{
+			Name:  "poll-interval",
+			Value: time.Second,
+			Usage: "Interval to scan watched files for --poll",
+		},
+		cli.BoolFlag{
+			Name:  "watch-trigger-all",
+			Usage: "Collect file changes in watch window, run each triggered task once",
+		},
+		cli.DurationFlag{
+			Name:  "watch-window",
+			Value: 200 * time.Millisecond,
+			Usage: "Window to collect file changes for --watch-trigger-all",
+		},
+		cli.BoolFlag{
+			Name:  "parallel, p",
+			Usage: "Run tasks specified in command line concurrently",
+		},
+		cli.StringFlag{
+			Name:  "serve",
+			Usage: "Serve HTTP control on address like :8090 (localhost if only port), POST /task/{name} and GET /status with Bearer token of .build/session.json",
+		},
+		cli.BoolFlag{
+			Name:  "tui",
+			Usage: "Show dashboard of tasks, daemons and their logs while watching, with keys to rerun and stop; linux and darwin only",
+		},
+		cli.StringFlag{
+			Name:  "socket",
+			Usage: "Serve control on unix socket like .build/build.sock, JSON requests per line to list, run, subscribe events and stop daemons",
+		},
+		cli.StringFlag{
+			Name:  "watch-policy",
+			Value: "queue",
+			Usage: "Watch trigger while task running: queue to run once after it, drop, or cancel running one and run again",
+		},
+		cli.IntFlag{
+			Name:  "max-parallel",
+			Usage: "Max watch triggered runs at the same time, 0 mean no limit",
+		},
+		cli.BoolFlag{
+			Name:  "notify",
+			Usage: "Send desktop notification when watch triggered task finish",
+		},
+		cli.BoolFlag{
+			Name:  "restart, r",
+			Usage: "Kill daemon commands of task before run the task again",
+		},
+		cli.DurationFlag{
+			Name:  "timeout, t",
+			Usage: "Max duration of each command except daemon, terminate command when exceed",
+		},
+		cli.BoolFlag{
+			Name:  "fail-fast",
+			Usage: "Exit on undefined task or variable even in watch mode",
+		},
+		cli.BoolFlag{
+			Name:  "exit-on-error",
+			Usage: "Exit with code of failed command even in watch mode",
+		},
+	}
+	// Arguments after -- pass to commands as ${ARGS}, split before cli
+	// parse as it consume -- if no task before it
+	var extraArgs []string
+	app.Action = func(c *cli.Context) {
+		// Get config file and task name from command line
+		var taskNames []string
+		// Arguments like KEY=VALUE override variables, same as --var
+		varOverrides := c.StringSlice("var")
+		for _, arg := range c.Args() {
+			if strings.Contains(arg, "=") {
+				varOverrides = append(varOverrides, arg)
+			} else {
+				taskNames = append(taskNames, arg)
+			}
+		}
+		if len(taskNames) == 0 {
+			taskNames = []string{"default"}
+		}
+		var pollInterval time.Duration
+		if c.Bool("poll") {
+			pollInterval = c.Duration("poll-interval")
+		}
+		err := builder.Configure(builder.Options{
+			EnvFile:         c.String("env-file"),
+			Vars:            varOverrides,
+			NoUserConfig:    c.Bool("no-user-config"),
+			Args:            extraArgs,
+			NoColor:         c.Bool("no-color"),
+			Silent:          c.Bool("silent"),
+			LogFormat:       c.String("log-format"),
+			Porcelain:       c.Bool("porcelain"),
+			LogFile:         c.String("log-file"),
+			LogMaxSize:      int64(c.Int("log-max-size")) * 1024 * 1024,
+			Timestamps:      c.Bool("timestamps"),
+			MaxLineLength:   c.Int("max-line-length"),
+			MaxLineRate:     c.Int("max-line-rate"),
+			Profile:         c.Bool("profile"),
+			Summary:         c.Bool("summary"),
+			Record:          c.Bool("record"),
+			Keep:            c.Bool("keep"),
+			Clear:           c.String("clear"),
+			MaxDuration:     c.Duration("max-duration"),
+			Timeout:         c.Duration("timeout"),
+			FailFast:        c.Bool("fail-fast"),
+			ExitOnError:     c.Bool("exit-on-error"),
+			DryRun:          c.Bool("dry-run"),
+			List:            c.Bool("list"),
+			Verbose:         c.Bool("verbose"),
+			Yes:             c.Bool("yes"),
+			NonInteractive:  c.Bool("non-interactive"),
+			Pty:             c.Bool("pty"),
+			Restart:         c.Bool("restart"),
+			DaemonBuffer:    c.Int("daemon-buffer"),
+			Parallel:        c.Bool("parallel"),
+			Notify:          c.Bool("notify"),
+			Debounce:        c.Duration("debounce"),
+			WatchTriggerAll: c.Bool("watch-trigger-all"),
+			WatchWindow:     c.Duration("watch-window"),
+			WatchPolicy:     c.String("watch-policy"),
+			MaxParallel:     c.Int("max-parallel"),
+			Poll:            pollInterval,
+			Once:            c.Bool("once"),
+		})
+		if err != nil {
+			builder.Log(builder.CLR_R, err.Error())
+			os.Exit(1)
+		}
+		if c.Bool("once") && c.Bool("watch-only") {
+			builder.Log(builder.CLR_R, "Flag --once Conflict With --watch-only")
+			os.Exit(1)
+		}
+		if c.Bool("tui") && c.Bool("porcelain") {
+			builder.Log(builder.CLR_R, "Flag --tui Conflict With --porcelain")
+			os.Exit(1)
+		}
+		// Parse config file and its include files, get build map
+		buildMap, err := builder.LoadConfig(c.String("config"))
+		if err != nil {
+			builder.Log(builder.CLR_R, err.Error())
+			os.Exit(1)
+		}
+		// Only list tasks if specified
+		if c.Bool("list") {
+			builder.ListTasks()
+			return
+		}
+		runner := &builder.Runner{}
+		// Only print commands of task if in dry run mode
+		if c.Bool("dry-run") {
+			if err := runner.RunTask(context.Background(), taskNames...); err != nil {
+				builder.Log(builder.CLR_R, err.Error())
+				os.Exit(1)
+			}
+			return
+		}
+		// Check instance already running in directory, watching instance
+		// must be unique by default
+		policy := c.String("instance")
+		if policy == "" && builder.KeepRunning() {
+			policy = "error"
+		}
+		if policy != "" {
+			forwarded, err := builder.CheckInstance(policy, taskNames)
+			if err != nil {
+				builder.Log(builder.CLR_R, err.Error())
+				os.Exit(1)
+			}
+			if forwarded {
+				return
+			}
+		}
+		// Clean up child processes when interrupted
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			sig := <-signals
+			builder.Log(builder.CLR_G, "Shutting Down By "+sig.String())
+			builder.Shutdown()
+			os.Exit(1)
+		}()
+		// Rerun last tasks or reload config by signal
+		rerun := make(chan os.Signal, 1)
+		reload := make(chan os.Signal, 1)
+		notifyControl(rerun, reload)
+		go func() {
+			for {
+				select {
+				case sig := <-rerun:
+					builder.Log(builder.CLR_G, "Rerun By "+sig.String())
+					builder.Rerun(nil)
+				case sig := <-reload:
+					builder.Log(builder.CLR_G, "Reload Config By "+sig.String())
+					builder.ReloadConfig()
+				}
+			}
+		}()
+		// Run tasks and exit, stop daemons of tasks too
+		if c.Bool("once") {
+			code, _ := builder.HandleError(runner.RunTask(context.Background(), taskNames...))
+			builder.Shutdown()
+			os.Exit(code)
+		}
+		// Start to watch file change, and serve control if specified
+		watcher := &builder.Watcher{}
+		if err := watcher.Start(context.Background()); err != nil {
+			builder.Log(builder.CLR_R, err.Error())
+			os.Exit(1)
+		}
+		if addr := c.String("serve"); addr != "" {
+			builder.ServeControl(addr)
+		}
+		if path := c.String("socket"); path != "" {
+			if err := builder.ServeSocket(path); err != nil {
+				builder.Log(builder.CLR_R, err.Error())
+				os.Exit(1)
+			}
+		}
+		if builder.KeepRunning() {
+			builder.StartSession()
+		}
+		useTUI := c.Bool("tui") && builder.KeepRunning()
+		if useTUI {
+			if err := builder.StartTUI(); err != nil {
+				builder.Log(builder.CLR_R, err.Error())
+				os.Exit(1)
+			}
+		}
+		// Run specified task, if not specified, run default task
+		if !c.Bool("watch-only") {
+			if code, exit := builder.HandleError(runner.RunTask(context.Background(), taskNames...)); exit {
+				builder.Shutdown()
+				os.Exit(code)
+			}
+		}
+		// Keep watch if has watch config, accept console input meanwhile
+		if len(buildMap.Watch) != 0 && builder.IsTerminal(os.Stdin) && !useTUI {
+			builder.StartConsole()
+		}
+		// Exit when console quit or watch triggered run request it
+		if builder.KeepRunning() {
+			os.Exit(<-builder.Exited())
+		}
+	}
+	app.Commands = shadowCommands(app, os.Args)
+	args := os.Args
+	for idx, arg := range os.Args {
+		if arg == "--" {
+			// Subcommand like exec parse -- by itself
+			if name, _ := firstArg(app, os.Args[:idx]); !hasCommand(app, name) {
+				args, extraArgs = os.Args[:idx], os.Args[idx+1:]
+			}
+			break
+		}
+	}
+	app.Run(args)
+}
+
+// Get first positional argument and config flag of command line, parsed by
+// global flags of app
+func firstArg(app *cli.App, args []string) (string, string) {
+	set := flag.NewFlagSet(app.Name, flag.ContinueOnError)
+	set.SetOutput(ioutil.Discard)
+	for _, f := range app.Flags {
+		f.Apply(set)
+	}
+	set.Bool("help", false, "")
+	set.Bool("h", false, "")
+	set.Bool("version", false, "")
+	set.Bool("v", false, "")
+	if set.Parse(args[1:]) != nil {
+		return "", ""
+	}
+	return set.Arg(0), set.Lookup("config").Value.String()
+}
+
+// Check if app has subcommand of name
+func hasCommand(app *cli.App, name string) bool {
+	for _, command := range app.Commands {
+		if command.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Drop subcommand of the first argument if config define task of the same
+// name, so the task run instead of the subcommand
+func shadowCommands(app *cli.App, args []string) []cli.Command {
+	name, config := firstArg(app, args)
+	if name == "" {
+		return app.Commands
+	}
+	var commands []cli.Command
+	for _, command := range app.Commands {
+		if command.Name == name && builder.HasTask(config, name) {
+			builder.Log(builder.CLR_W, "Task \""+name+"\" Shadows Subcommand \""+name+"\"")
+			continue
+		}
+		commands = append(commands, command)
+	}
+	return commands
+}
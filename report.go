@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"github.com/codegangsta/cli"
+	"github.com/imeoer/build.go/builder"
+	"os"
+	"sort"
+	"time"
+)
+
+// Subcommand summarize build runs recorded by --record
+var reportCommand = cli.Command{
+	Name:  "report",
+	Usage: "Summarize recent build runs recorded by --record: failure rate and time of tasks, slowest commands",
+	Flags: []cli.Flag{
+		cli.IntFlag{
+			Name:  "last, n",
+			Usage: "Only summarize last count of runs, 0 mean all",
+		},
+		cli.DurationFlag{
+			Name:  "since",
+			Usage: "Only summarize runs in duration, like 168h for last week",
+		},
+		cli.IntFlag{
+			Name:  "top",
+			Value: 10,
+			Usage: "Count of slowest commands listed",
+		},
+	},
+	Action: func(c *cli.Context) {
+		builder.EnterProjectDir(c.GlobalString("config"))
+		runs, err := builder.ReadHistory()
+		if os.IsNotExist(err) {
+			builder.Log(builder.CLR_R, "No History, Run With --record First")
+			os.Exit(1)
+		}
+		if err != nil {
+			builder.Log(builder.CLR_R, err.Error())
+			os.Exit(1)
+		}
+		if since := c.Duration("since"); since > 0 {
+			from := time.Now().Add(-since)
+			for len(runs) > 0 && runs[0].Time.Before(from) {
+				runs = runs[1:]
+			}
+		}
+		if last := c.Int("last"); last > 0 && len(runs) > last {
+			runs = runs[len(runs)-last:]
+		}
+		if len(runs) == 0 {
+			fmt.Println("No Recorded Run")
+			return
+		}
+		printReport(runs, c.Int("top"))
+	},
+}
+
+// Time and failures of task or command in recorded runs, elapsed are of
+// succeed ones in run order
+type reportStat struct {
+	name    string
+	command string
+	runs    int
+	failed  int
+	elapsed []float64
+}
+
+// Add result of a run to stat
+func (stat *reportStat) add(failed bool, elapsed float64) {
+	stat.runs++
+	if failed {
+		stat.failed++
+	} else {
+		stat.elapsed = append(stat.elapsed, elapsed)
+	}
+}
+
+// Average elapsed of last count succeed runs, all if count is 0
+func (stat *reportStat) average(count int) time.Duration {
+	values := stat.elapsed
+	if count > 0 && len(values) > count {
+		values = values[len(values)-count:]
+	}
+	if len(values) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, value := range values {
+		total += value
+	}
+	return millis(total / float64(len(values)))
+}
+
+// Max elapsed of succeed runs
+func (stat *reportStat) max() time.Duration {
+	max := 0.0
+	for _, value := range stat.elapsed {
+		if value > max {
+			max = value
+		}
+	}
+	return millis(max)
+}
+
+// Failure rate in percent
+func (stat *reportStat) failRate() float64 {
+	if stat.runs == 0 {
+		return 0
+	}
+	return float64(stat.failed) * 100 / float64(stat.runs)
+}
+
+// Convert milliseconds of history to duration, round for print
+func millis(value float64) time.Duration {
+	return time.Duration(value * float64(time.Millisecond)).Round(time.Millisecond)
+}
+
+// Format duration of stat, - if no succeed run
+func (stat *reportStat) format(value time.Duration) string {
+	if len(stat.elapsed) == 0 {
+		return "-"
+	}
+	return value.String()
+}
+
+// Print runs overview, stat of tasks by name, and slowest commands by
+// average time
+func printReport(runs []builder.HistoryRun, top int) {
+	var tasks, commands []*reportStat
+	taskStats := make(map[string]*reportStat)
+	commandStats := make(map[string]*reportStat)
+	failed, canceled := 0, 0
+	for _, run := range runs {
+		switch run.Status {
+		case "failed":
+			failed++
+		case "canceled":
+			canceled++
+		}
+		for _, result := range run.Results {
+			// Skipped, up to date and cached task run nothing
+			if result.Status != "done" && result.Status != "failed" {
+				continue
+			}
+			stat, ok := taskStats[result.Task]
+			if !ok {
+				stat = &reportStat{name: result.Task}
+				taskStats[result.Task] = stat
+				tasks = append(tasks, stat)
+			}
+			stat.add(result.Status == "failed", result.ElapsedMs)
+			for _, command := range result.Commands {
+				key := command.Name + " " + command.Command
+				stat, ok := commandStats[key]
+				if !ok {
+					stat = &reportStat{name: command.Name, command: command.Command}
+					commandStats[key] = stat
+					commands = append(commands, stat)
+				}
+				stat.add(command.ExitCode != 0, command.ElapsedMs)
+			}
+		}
+	}
+	fmt.Printf("Runs %d Since %s, Failed %d (%.1f%%), Canceled %d\n", len(runs), runs[0].Time.Format("2006-01-02 15:04"), failed, float64(failed)*100/float64(len(runs)), canceled)
+	fmt.Println()
+	width := len("TASK")
+	for _, stat := range tasks {
+		if len(stat.name) > width {
+			width = len(stat.name)
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].average(0) > tasks[j].average(0)
+	})
+	// Recent is average of last runs, compare with average for regression
+	fmt.Printf("%-*s  %5s  %6s  %6s  %10s  %10s  %10s\n", width, "TASK", "RUNS", "FAILED", "FAIL%", "AVG", "RECENT", "MAX")
+	for _, stat := range tasks {
+		fmt.Printf("%-*s  %5d  %6d  %5.1f%%  %10s  %10s  %10s\n", width, stat.name, stat.runs, stat.failed, stat.failRate(), stat.format(stat.average(0)), stat.format(stat.average(5)), stat.format(stat.max()))
+	}
+	if top <= 0 || len(commands) == 0 {
+		return
+	}
+	sort.SliceStable(commands, func(i, j int) bool {
+		return commands[i].average(0) > commands[j].average(0)
+	})
+	if len(commands) > top {
+		commands = commands[:top]
+	}
+	width = len("STEP")
+	for _, stat := range commands {
+		if len(stat.name) > width {
+			width = len(stat.name)
+		}
+	}
+	fmt.Println()
+	fmt.Printf("%-*s  %5s  %6s  %10s  %10s  %s\n", width, "STEP", "RUNS", "FAILED", "AVG", "MAX", "COMMAND")
+	for _, stat := range commands {
+		fmt.Printf("%-*s  %5d  %6d  %10s  %10s  %s\n", width, stat.name, stat.runs, stat.failed, stat.format(stat.average(0)), stat.format(stat.max()), stat.command)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"github.com/codegangsta/cli"
+	"github.com/imeoer/build.go/builder"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Starter configs of common stacks, used by init subcommand
+var scaffolds = map[string]string{
+	"basic": `# Build.go config, run "build.go --list" to show tasks
+
+variable:
+    name: "world"
+
+task:
+    default:
+        - "${hello}"
+    hello:
+        - "echo hello ${name}"
+
+watch:
+    "*.txt": "${default}"
+`,
+	"go": `# Build.go config for Go project, run with --restart to restart server
+# when source changed
+
+variable:
+    bin: "./app"
+
+task:
+    default:
+        - "${build}"
+        - "${#run}"
+    build:
+        sources: ["**/*.go", "go.mod"]
+        generates: ["${bin}"]
+        cmds:
+            - "go build -o ${bin} ."
+    run:
+        - "exec ${bin}"
+    test:
+        - "go test ./..."
+
+watch:
+    "**/*.go": "${default}"
+
+ignore:
+    - ".git"
+    - "vendor"
+`,
+	"node": `# Build.go config for Node.js project, run with --restart to restart
+# server when source changed
+
+variable:
+    port: "${PORT:-3000}"
+
+task:
+    default:
+        - "${install}"
+        - "${#start}"
+    install:
+        sources: ["package.json", "package-lock.json"]
+        generates: ["node_modules"]
+        cmds:
+            - "npm install"
+    start:
+        env:
+            PORT: "${port}"
+        cmds:
+            - "npm start"
+    test:
+        - "npm test"
+
+watch:
+    "src/**/*.js": "${default}"
+    "package.json": "${install}"
+
+ignore:
+    - ".git"
+    - "node_modules"
+`,
+	"static-site": `# Build.go config for static site, copy src to dist and serve it, browser
+# reload by LiveReload after build
+
+livereload: true
+
+variable:
+    src: "src"
+    dist: "dist"
+
+task:
+    default:
+        - "${build}"
+        - "${serve}"
+    build:
+        - "mkdir -p ${dist}"
+        - "cp -R ${src}/. ${dist}/"
+    serve:
+        serve:
+            dir: "${dist}"
+            port: 8080
+
+watch:
+    "${src}/**/*": "${build}"
+`,
+}
+
+// Subcommand write starter config from template
+var initCommand = cli.Command{
+	Name:  "init",
+	Usage: "Write starter config from template: " + strings.Join(scaffoldNames(), ", ") + "; default is basic",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "force, f",
+			Usage: "Overwrite config file if exists",
+		},
+	},
+	Action: func(c *cli.Context) {
+		name := c.Args().First()
+		if name == "" {
+			name = "basic"
+		}
+		content, ok := scaffolds[name]
+		if !ok {
+			builder.Log(builder.CLR_R, "Template \""+name+"\" Not Found, Expect "+strings.Join(scaffoldNames(), ", "))
+			os.Exit(1)
+		}
+		path := builder.ExpandPath(c.GlobalString("config"))
+		if _, err := os.Stat(path); err == nil && !c.Bool("force") {
+			builder.Log(builder.CLR_R, "Config \""+path+"\" Already Exists, Use --force to Overwrite")
+			os.Exit(1)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			builder.Log(builder.CLR_R, err.Error())
+			os.Exit(1)
+		}
+		builder.Log(builder.CLR_W, "Config \""+path+"\" Created From "+name+" Template")
+	},
+}
+
+// Get names of templates, sorted
+func scaffoldNames() []string {
+	names := make([]string, 0, len(scaffolds))
+	for name := range scaffolds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
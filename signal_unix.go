@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Notify SIGUSR1 to rerun last tasks, and SIGHUP to reload config
+func notifyControl(rerun chan os.Signal, reload chan os.Signal) {
+	signal.Notify(rerun, syscall.SIGUSR1)
+	signal.Notify(reload, syscall.SIGHUP)
+}
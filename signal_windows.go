@@ -0,0 +1,9 @@
+package main
+
+import (
+	"os"
+)
+
+// Windows has no SIGUSR1 and SIGHUP, rerun and reload by console instead
+func notifyControl(rerun chan os.Signal, reload chan os.Signal) {
+}
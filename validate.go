@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"github.com/codegangsta/cli"
+	"github.com/imeoer/build.go/builder"
+	"os"
+)
+
+// Subcommand check config without run any command
+var validateCommand = cli.Command{
+	Name:  "validate",
+	Usage: "Check config for undefined variables and tasks, circular references and watch patterns match no file",
+	Action: func(c *cli.Context) {
+		err := builder.Configure(builder.Options{
+			EnvFile:      c.GlobalString("env-file"),
+			Vars:         c.GlobalStringSlice("var"),
+			NoUserConfig: c.GlobalBool("no-user-config"),
+		})
+		if err != nil {
+			builder.Log(builder.CLR_R, err.Error())
+			os.Exit(1)
+		}
+		problems := builder.Validate(c.GlobalString("config"))
+		for _, problem := range problems {
+			builder.Log(builder.CLR_R, problem)
+		}
+		if len(problems) > 0 {
+			builder.Log(builder.CLR_R, fmt.Sprintf("%d Problems Found", len(problems)))
+			os.Exit(1)
+		}
+		builder.Log(builder.CLR_W, "Config Valid")
+	},
+}